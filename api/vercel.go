@@ -43,6 +43,10 @@ func init() {
 		log.Fatalf("Could not connect to the database: %v", err)
 	}
 
+	if err := utils.RegisterValidators(); err != nil {
+		log.Fatalf("Could not register validators: %v", err)
+	}
+
 	App = routes.SetupRouter()
 }
 
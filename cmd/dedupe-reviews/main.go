@@ -0,0 +1,31 @@
+// Command dedupe-reviews is a one-off migration that merges the duplicate
+// reviews users left on the same phone before reviews were unique per user
+// and phone. Run it once, before deploying, when the app refuses to start
+// with config.ErrDuplicateReviews.
+package main
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/utils"
+	"log"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if utils.Getenv("ENVIRONMENT", "development") == "development" {
+		if err := godotenv.Load(); err != nil {
+			log.Fatal("Error loading .env file")
+		}
+	}
+
+	if err := config.OpenDataBase(); err != nil {
+		log.Fatalf("Could not connect to the database: %v", err)
+	}
+
+	removed, err := config.DedupeReviews(config.DB)
+	if err != nil {
+		log.Fatalf("Merging duplicate reviews failed: %v", err)
+	}
+	log.Printf("Removed %d duplicate reviews", removed)
+}
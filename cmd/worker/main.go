@@ -0,0 +1,75 @@
+// Command worker runs the background jobs queued in the outbox and queues
+// the email digests that are due. It polls until interrupted, or runs the
+// due jobs once with -once.
+package main
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/controllers"
+	"backend-vercel-phone-review/utils"
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// digestInterval is how often the worker looks for digests that are due.
+const digestInterval = time.Minute
+
+func main() {
+	once := flag.Bool("once", false, "run the jobs that are due and exit")
+	interval := flag.Duration("interval", 5*time.Second, "wait between polls when the queue is empty")
+	flag.Parse()
+
+	if utils.Getenv("ENVIRONMENT", "development") == "development" {
+		if err := godotenv.Load(); err != nil {
+			log.Fatal("Error loading .env file")
+		}
+	}
+
+	if err := config.ConnectDataBase(); err != nil {
+		log.Fatalf("Could not connect to the database: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	worker := controllers.NewWorker()
+	var digestsQueuedAt time.Time
+	for {
+		if time.Since(digestsQueuedAt) >= digestInterval {
+			queued, err := controllers.QueueDueDigests(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Queueing digests failed: %v", err)
+			} else if queued > 0 {
+				log.Printf("Queued %d digests", queued)
+			}
+			digestsQueuedAt = time.Now()
+		}
+
+		summary, err := worker.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Job run failed: %v", err)
+		}
+		if summary.Claimed > 0 {
+			log.Printf("Ran %d jobs: %d done, %d retried, %d dead", summary.Claimed, summary.Done, summary.Retried, summary.Dead)
+		}
+
+		if *once && summary.Claimed == 0 {
+			return
+		}
+		if summary.Claimed > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
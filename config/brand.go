@@ -0,0 +1,68 @@
+package config
+
+import (
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// normalizeBrands links every phone that has no brand yet to the brand its
+// free text brand slugifies to, creating the brand under the spelling most
+// of its phones use, and rewrites the phones' brand to that spelling. It is
+// a no-op once all phones are linked.
+func normalizeBrands(db *gorm.DB) error {
+	var phones []models.Phone
+	if err := db.Unscoped().Select("id", "brand").Where("brand_id = 0 OR brand_id IS NULL").Find(&phones).Error; err != nil {
+		return err
+	}
+
+	bySlug := make(map[string][]models.Phone)
+	for _, phone := range phones {
+		if slug := utils.Slugify(phone.Brand); slug != "" {
+			bySlug[slug] = append(bySlug[slug], phone)
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for slug, phones := range bySlug {
+			brand := models.Brand{Slug: slug, Name: commonSpelling(phones)}
+			if err := tx.Where(models.Brand{Slug: slug}).Attrs(brand).FirstOrCreate(&brand).Error; err != nil {
+				return err
+			}
+
+			ids := make([]uint, len(phones))
+			for i, phone := range phones {
+				ids[i] = phone.ID
+			}
+			if err := tx.Unscoped().Model(&models.Phone{}).Where("id IN ?", ids).
+				Updates(map[string]interface{}{"brand_id": brand.ID, "brand": brand.Name}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// commonSpelling picks the trimmed brand spelling most phones use, the
+// alphabetically first one on a tie.
+func commonSpelling(phones []models.Phone) string {
+	counts := make(map[string]int)
+	for _, phone := range phones {
+		counts[strings.Join(strings.Fields(phone.Brand), " ")]++
+	}
+
+	spellings := make([]string, 0, len(counts))
+	for spelling := range counts {
+		spellings = append(spellings, spelling)
+	}
+	sort.Slice(spellings, func(i, j int) bool {
+		if counts[spellings[i]] != counts[spellings[j]] {
+			return counts[spellings[i]] > counts[spellings[j]]
+		}
+		return spellings[i] < spellings[j]
+	})
+	return spellings[0]
+}
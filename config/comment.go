@@ -0,0 +1,17 @@
+package config
+
+import (
+	"backend-vercel-phone-review/utils"
+	"strconv"
+)
+
+// CommentMaxDepth is how deeply replies may nest, read from
+// COMMENT_MAX_DEPTH. Top-level comments have depth 0, so with the default
+// of 5 a reply can be at most 4 levels below them.
+func CommentMaxDepth() int {
+	depth, err := strconv.Atoi(utils.Getenv("COMMENT_MAX_DEPTH", "5"))
+	if err != nil || depth < 1 {
+		return 5
+	}
+	return depth
+}
@@ -15,9 +15,63 @@ import (
 var DB *gorm.DB
 
 func ConnectDataBase() error {
+	dbProvider, dsn, err := openDataBase()
+	if err != nil {
+		return err
+	}
+
+	err = connectEvents(dbProvider, dsn)
+	if err != nil {
+		log.Printf("Error connecting the event broker: %v", err)
+		return err
+	}
+
+	err = checkDuplicateReviews(DB)
+	if err != nil {
+		log.Printf("Error checking for duplicate reviews: %v", err)
+		return err
+	}
+
+	err = dedupeSubRatings(DB)
+	if err != nil {
+		log.Printf("Error removing duplicate sub-ratings: %v", err)
+		return err
+	}
+
+	// Auto migrate models
+	err = DB.AutoMigrate(&models.User{}, &models.Profile{}, &models.Comment{}, &models.Brand{}, &models.Phone{}, &models.Feature{}, &models.Review{}, &models.SubRating{}, &models.ReviewVote{}, &models.ReviewRevision{}, &models.Report{}, &models.Mention{}, &models.Notification{}, &models.NotificationPreference{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.OutboxJob{}, &models.Follow{}, &models.DigestSubscription{}, &models.SpecDefinition{}, &models.SpecValue{}, &models.Variant{}, &models.VariantSpecValue{})
+	if err != nil {
+		log.Printf("Error during migration: %v", err)
+		return err
+	}
+
+	err = normalizeBrands(DB)
+	if err != nil {
+		log.Printf("Error normalizing phone brands: %v", err)
+		return err
+	}
+
+	err = seedSpecDefinitions(DB)
+	if err != nil {
+		log.Printf("Error seeding the spec registry: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// OpenDataBase connects DB without migrating it, for one-off commands that
+// must run before the models are migrated.
+func OpenDataBase() error {
+	_, _, err := openDataBase()
+	return err
+}
+
+func openDataBase() (string, string, error) {
 	dbProvider := utils.Getenv("DB_PROVIDER", "mysql")
 
 	var db *gorm.DB
+	var dsn string
 	var err error
 
 	if dbProvider == "postgres" {
@@ -27,11 +81,11 @@ func ConnectDataBase() error {
 		port := os.Getenv("DB_PORT")
 		database := os.Getenv("DB_NAME")
 		// production
-		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=require", host, username, password, database, port)
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=require", host, username, password, database, port)
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 		if err != nil {
 			log.Printf("Error connecting to PostgreSQL: %v", err)
-			return err
+			return "", "", err
 		}
 	} else {
 		username := utils.Getenv("DB_USERNAME", "root")
@@ -40,23 +94,16 @@ func ConnectDataBase() error {
 		port := utils.Getenv("DB_PORT", "3306")
 		database := utils.Getenv("DB_NAME", "db_name")
 
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", username, password, host, port, database)
-		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", username, password, host, port, database)
+		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 		if err != nil {
 			log.Printf("Error connecting to MySQL: %v", err)
-			return err
+			return "", "", err
 		}
 	}
 
 	// Set the global DB variable
 	DB = db
 
-	// Auto migrate models
-	err = DB.AutoMigrate(&models.User{}, &models.Profile{}, &models.Comment{}, &models.Phone{}, &models.Feature{}, &models.Review{})
-	if err != nil {
-		log.Printf("Error during migration: %v", err)
-		return err
-	}
-
-	return nil
+	return dbProvider, dsn, nil
 }
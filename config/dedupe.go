@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateReviews stops the migration while users still have several
+// reviews of the same phone, which the unique index on reviews forbids.
+var ErrDuplicateReviews = errors.New("reviews holds several reviews per user and phone; run go run ./cmd/dedupe-reviews before starting the app")
+
+// checkDuplicateReviews fails with ErrDuplicateReviews while the unique
+// (user_id, phone_id) index is missing and rows would violate it. It is a
+// no-op once the index exists.
+func checkDuplicateReviews(db *gorm.DB) error {
+	if !db.Migrator().HasTable("reviews") || db.Migrator().HasIndex("reviews", "idx_reviews_user_phone") {
+		return nil
+	}
+
+	var duplicates []uint
+	if err := db.Table("reviews").Group("user_id, phone_id").Having("COUNT(*) > 1").Limit(1).Pluck("user_id", &duplicates).Error; err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return ErrDuplicateReviews
+	}
+	return nil
+}
+
+// dedupeSubRatings keeps only the latest sub-rating of every (review_id,
+// dimension) pair so the unique index on sub_ratings can be created. A
+// review's sub-ratings are always replaced together, so the older rows are
+// leftovers of concurrent saves. It is a no-op once the index exists.
+func dedupeSubRatings(db *gorm.DB) error {
+	if !db.Migrator().HasTable("sub_ratings") || db.Migrator().HasIndex("sub_ratings", "idx_sub_ratings_review_dimension") {
+		return nil
+	}
+
+	return db.Exec(`DELETE FROM sub_ratings WHERE id NOT IN (
+		SELECT id FROM (SELECT MAX(id) AS id FROM sub_ratings GROUP BY review_id, dimension) AS latest
+	)`).Error
+}
+
+// reviewChild is a table whose rows belong to a review. Owner names the
+// column that is unique per review, such as the voter of a vote; it is
+// empty for tables that allow any number of rows per review. Drop marks
+// rows that only make sense for their own review.
+type reviewChild struct {
+	Table  string
+	Column string
+	Owner  string
+	Where  string
+	Drop   bool
+}
+
+var reviewChildren = []reviewChild{
+	{Table: "sub_ratings", Column: "review_id", Drop: true},
+	{Table: "review_revisions", Column: "review_id", Drop: true},
+	{Table: "review_votes", Column: "review_id", Owner: "user_id"},
+	{Table: "reports", Column: "target_id", Owner: "reporter_id", Where: "target_type = 'review'"},
+	{Table: "comments", Column: "review_id"},
+	{Table: "notifications", Column: "review_id"},
+}
+
+// DedupeReviews keeps one review of every (user_id, phone_id) pair so the
+// unique index on reviews can be created: the newest one that is not
+// deleted, or the newest one when all are. Comments, votes, reports and
+// notifications of the other reviews move to the kept one, while their
+// sub-ratings and revisions go with them. It returns how many reviews were
+// removed.
+func DedupeReviews(db *gorm.DB) (int, error) {
+	removed := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var pairs []struct {
+			UserID  uint
+			PhoneID uint
+		}
+		if err := tx.Table("reviews").Select("user_id, phone_id").Group("user_id, phone_id").Having("COUNT(*) > 1").Scan(&pairs).Error; err != nil {
+			return err
+		}
+
+		for _, pair := range pairs {
+			var rows []struct {
+				ID        uint
+				DeletedAt gorm.DeletedAt
+			}
+			if err := tx.Table("reviews").Select("id, deleted_at").
+				Where("user_id = ? AND phone_id = ?", pair.UserID, pair.PhoneID).
+				Order("id DESC").Scan(&rows).Error; err != nil {
+				return err
+			}
+
+			keep := rows[0].ID
+			for _, row := range rows {
+				if !row.DeletedAt.Valid {
+					keep = row.ID
+					break
+				}
+			}
+
+			for _, row := range rows {
+				if row.ID == keep {
+					continue
+				}
+				if err := mergeReview(tx, keep, row.ID); err != nil {
+					return fmt.Errorf("merging review %d into %d: %w", row.ID, keep, err)
+				}
+				removed++
+			}
+
+			if tx.Migrator().HasTable("review_votes") {
+				if err := tx.Exec(`UPDATE reviews SET
+					helpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = ? AND helpful = ? AND deleted_at IS NULL),
+					not_helpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = ? AND helpful = ? AND deleted_at IS NULL)
+					WHERE id = ?`, keep, true, keep, false, keep).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// mergeReview moves the children of the review drop to keep and deletes it.
+func mergeReview(tx *gorm.DB, keep, drop uint) error {
+	for _, child := range reviewChildren {
+		if !tx.Migrator().HasTable(child.Table) {
+			continue
+		}
+
+		of := child.Column + " = ?"
+		if child.Where != "" {
+			of += " AND " + child.Where
+		}
+
+		if child.Drop {
+			if err := tx.Exec("DELETE FROM "+child.Table+" WHERE "+of, drop).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		// An owner who already has a row on the kept review keeps that one
+		if child.Owner != "" {
+			var owners []uint
+			if err := tx.Table(child.Table).Where(of, keep).Pluck(child.Owner, &owners).Error; err != nil {
+				return err
+			}
+			if len(owners) > 0 {
+				if err := tx.Exec("DELETE FROM "+child.Table+" WHERE "+of+" AND "+child.Owner+" IN ?", drop, owners).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Exec("UPDATE "+child.Table+" SET "+child.Column+" = ? WHERE "+of, keep, drop).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Exec("DELETE FROM reviews WHERE id = ?", drop).Error
+}
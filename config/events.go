@@ -0,0 +1,42 @@
+package config
+
+import (
+	"backend-vercel-phone-review/events"
+	"backend-vercel-phone-review/utils"
+	"log"
+)
+
+const (
+	EventsBrokerMemory   = "memory"
+	EventsBrokerPostgres = "postgres"
+)
+
+// Events carries real-time updates to the SSE streams.
+var Events events.Broker = events.NewMemory()
+
+// connectEvents picks the event broker from EVENTS_BROKER. The in-process
+// broker only reaches clients connected to the same instance; the postgres
+// one shares events between instances through LISTEN/NOTIFY and needs the
+// postgres database provider. Notifications created by background jobs are
+// published by the outbox worker, so when it runs as the separate worker
+// command those only reach the streams through the postgres broker.
+func connectEvents(dbProvider, dsn string) error {
+	broker := utils.Getenv("EVENTS_BROKER", EventsBrokerMemory)
+	if broker != EventsBrokerPostgres {
+		Events = events.NewMemory()
+		return nil
+	}
+
+	if dbProvider != "postgres" {
+		log.Printf("EVENTS_BROKER=postgres needs DB_PROVIDER=postgres, falling back to the in-process broker")
+		Events = events.NewMemory()
+		return nil
+	}
+
+	postgres, err := events.NewPostgres(DB, dsn)
+	if err != nil {
+		return err
+	}
+	Events = postgres
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"backend-vercel-phone-review/mailer"
+	"backend-vercel-phone-review/utils"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MailDriverFile = "file"
+	MailDriverSMTP = "smtp"
+)
+
+// NewMailer picks how email is sent from MAIL_DRIVER. The file driver, the
+// default, writes messages to MAIL_DIR; the smtp driver sends them through
+// SMTP_HOST and SMTP_PORT, logging in with SMTP_USERNAME and SMTP_PASSWORD.
+func NewMailer() mailer.Mailer {
+	if utils.Getenv("MAIL_DRIVER", MailDriverFile) == MailDriverSMTP {
+		return mailer.SMTP{
+			Addr:     net.JoinHostPort(utils.Getenv("SMTP_HOST", "localhost"), utils.Getenv("SMTP_PORT", "587")),
+			Username: utils.Getenv("SMTP_USERNAME", ""),
+			Password: utils.Getenv("SMTP_PASSWORD", ""),
+		}
+	}
+
+	return mailer.FileSink{Dir: utils.Getenv("MAIL_DIR", filepath.Join(os.TempDir(), "phone-review-mail"))}
+}
+
+// MailFrom is the sender of outgoing email, read from MAIL_FROM.
+func MailFrom() string {
+	return utils.Getenv("MAIL_FROM", "Phone Review <no-reply@localhost>")
+}
+
+// PublicURL is the address the API is reached at from outside, used for
+// links in email. It is read from PUBLIC_URL.
+func PublicURL() string {
+	return strings.TrimSuffix(utils.Getenv("PUBLIC_URL", "http://localhost:8080"), "/")
+}
+
+var ErrNoMailTokenSecret = errors.New("neither MAIL_TOKEN_SECRET nor JWT_SECRET is set")
+
+// MailTokenSecret signs the links in email, such as unsubscribe links. It
+// is read from MAIL_TOKEN_SECRET and falls back to JWT_SECRET. Links are
+// never signed with an empty key: without either, it fails.
+func MailTokenSecret() ([]byte, error) {
+	secret := utils.Getenv("MAIL_TOKEN_SECRET", os.Getenv("JWT_SECRET"))
+	if secret == "" {
+		return nil, ErrNoMailTokenSecret
+	}
+	return []byte(secret), nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"backend-vercel-phone-review/utils"
+	"strconv"
+)
+
+const (
+	AutoPublishAll     = "all"
+	AutoPublishTrusted = "trusted"
+	AutoPublishNone    = "none"
+)
+
+type ModerationRules struct {
+	// AutoPublish is one of all, trusted or none
+	AutoPublish string
+	// TrustedAfter is the number of published reviews after which a
+	// user's new reviews skip the queue in trusted mode
+	TrustedAfter int64
+}
+
+// ReviewModerationRules reads the auto-publish rules for new and edited
+// reviews from REVIEW_AUTO_PUBLISH and REVIEW_TRUSTED_AFTER.
+func ReviewModerationRules() ModerationRules {
+	rules := ModerationRules{
+		AutoPublish:  utils.Getenv("REVIEW_AUTO_PUBLISH", AutoPublishTrusted),
+		TrustedAfter: 3,
+	}
+
+	if value, err := strconv.ParseInt(utils.Getenv("REVIEW_TRUSTED_AFTER", ""), 10, 64); err == nil && value >= 0 {
+		rules.TrustedAfter = value
+	}
+
+	return rules
+}
+
+// ReportHideThreshold is the number of open reports after which a review or
+// comment is hidden until a moderator looks at it, read from
+// REPORT_HIDE_THRESHOLD. Zero disables auto-hiding.
+func ReportHideThreshold() int64 {
+	threshold, err := strconv.ParseInt(utils.Getenv("REPORT_HIDE_THRESHOLD", "3"), 10, 64)
+	if err != nil || threshold < 0 {
+		return 3
+	}
+	return threshold
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"backend-vercel-phone-review/utils"
+	"time"
+)
+
+type OutboxPolicy struct {
+	// BatchSize is how many jobs one worker pass claims at most
+	BatchSize int
+	// MaxAttempts is how many times a job runs before it is dead-lettered
+	MaxAttempts int
+	// BaseDelay is the wait before a failed job's first retry, doubled for
+	// each one after it up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Lease is how long a claimed job is left alone before another worker
+	// may assume its worker died
+	Lease time.Duration
+}
+
+// OutboxWorker reads the background job settings from OUTBOX_BATCH_SIZE,
+// OUTBOX_MAX_ATTEMPTS, OUTBOX_RETRY_BASE_SECONDS, OUTBOX_RETRY_MAX_SECONDS
+// and OUTBOX_LEASE_SECONDS.
+func OutboxWorker() OutboxPolicy {
+	return OutboxPolicy{
+		BatchSize:   positiveInt("OUTBOX_BATCH_SIZE", 50),
+		MaxAttempts: positiveInt("OUTBOX_MAX_ATTEMPTS", 8),
+		BaseDelay:   time.Duration(positiveInt("OUTBOX_RETRY_BASE_SECONDS", 30)) * time.Second,
+		MaxDelay:    time.Duration(positiveInt("OUTBOX_RETRY_MAX_SECONDS", 3600)) * time.Second,
+		Lease:       time.Duration(positiveInt("OUTBOX_LEASE_SECONDS", 300)) * time.Second,
+	}
+}
+
+// CronSecret is the bearer token the scheduler must send to trigger the
+// job runner endpoint, read from CRON_SECRET. The endpoint is disabled when
+// it is empty.
+func CronSecret() string {
+	return utils.Getenv("CRON_SECRET", "")
+}
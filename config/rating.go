@@ -0,0 +1,23 @@
+package config
+
+import (
+	"backend-vercel-phone-review/utils"
+	"strings"
+)
+
+const defaultRatingDimensions = "camera,battery,display,performance,value"
+
+// RatingDimensions returns the sub-rating dimensions a review may score,
+// read from RATING_DIMENSIONS as a comma separated list.
+func RatingDimensions() []string {
+	raw := utils.Getenv("RATING_DIMENSIONS", defaultRatingDimensions)
+
+	var dimensions []string
+	for _, dimension := range strings.Split(raw, ",") {
+		dimension = strings.ToLower(strings.TrimSpace(dimension))
+		if dimension != "" {
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	return dimensions
+}
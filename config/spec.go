@@ -0,0 +1,44 @@
+package config
+
+import (
+	"backend-vercel-phone-review/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultSpecDefinitions fills an empty spec registry. Admins can change
+// the registry afterwards through the API.
+var defaultSpecDefinitions = []models.SpecDefinition{
+	{Key: "display_size", Label: "Screen size", DataType: models.SpecNumber, Unit: "in", Group: "Display", Position: 1},
+	{Key: "display_resolution", Label: "Resolution", DataType: models.SpecText, Group: "Display", Position: 2},
+	{Key: "refresh_rate", Label: "Refresh rate", DataType: models.SpecInteger, Unit: "Hz", Group: "Display", Better: models.SpecHigherIsBetter, Position: 3},
+	{Key: "main_camera", Label: "Main camera", DataType: models.SpecNumber, Unit: "MP", Group: "Camera", Better: models.SpecHigherIsBetter, Position: 1},
+	{Key: "front_camera", Label: "Front camera", DataType: models.SpecNumber, Unit: "MP", Group: "Camera", Better: models.SpecHigherIsBetter, Position: 2},
+	{Key: "battery_capacity", Label: "Battery capacity", DataType: models.SpecInteger, Unit: "mAh", Group: "Battery", Better: models.SpecHigherIsBetter, Position: 1},
+	{Key: "charging_power", Label: "Wired charging", DataType: models.SpecNumber, Unit: "W", Group: "Battery", Better: models.SpecHigherIsBetter, Position: 2},
+	{Key: "wireless_charging", Label: "Wireless charging", DataType: models.SpecBoolean, Group: "Battery", Better: models.SpecHigherIsBetter, Position: 3},
+	{Key: "chipset", Label: "Chipset", DataType: models.SpecText, Group: "Performance", Position: 1},
+	{Key: "ram", Label: "RAM", DataType: models.SpecInteger, Unit: "GB", Group: "Performance", Better: models.SpecHigherIsBetter, Position: 2},
+	{Key: "storage", Label: "Storage", DataType: models.SpecInteger, Unit: "GB", Group: "Performance", Better: models.SpecHigherIsBetter, Position: 3},
+	{Key: "height", Label: "Height", DataType: models.SpecNumber, Unit: "mm", Group: "Body", Position: 1},
+	{Key: "weight", Label: "Weight", DataType: models.SpecNumber, Unit: "g", Group: "Body", Better: models.SpecLowerIsBetter, Position: 2},
+	{Key: "water_resistance", Label: "Water resistance", DataType: models.SpecText, Group: "Body", Position: 3},
+	{Key: "5g", Label: "5G", DataType: models.SpecBoolean, Group: "Connectivity", Better: models.SpecHigherIsBetter, Position: 1},
+	{Key: "os", Label: "Operating system", DataType: models.SpecEnum, Group: "Software", Options: []string{"Android", "iOS", "Other"}, Position: 1},
+}
+
+// seedSpecDefinitions adds the default specs when the registry has never
+// had any, so specs an admin removed do not come back.
+func seedSpecDefinitions(db *gorm.DB) error {
+	var count int64
+	if err := db.Unscoped().Model(&models.SpecDefinition{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	definitions := make([]models.SpecDefinition, len(defaultSpecDefinitions))
+	copy(definitions, defaultSpecDefinitions)
+	return db.Create(&definitions).Error
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"backend-vercel-phone-review/utils"
+	"strconv"
+	"time"
+)
+
+type WebhookRetryPolicy struct {
+	// MaxAttempts is how many times a delivery is tried before it fails
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubled for each one
+	// after it up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout bounds a single attempt
+	Timeout time.Duration
+}
+
+// WebhookRetries reads the webhook retry policy from WEBHOOK_MAX_ATTEMPTS,
+// WEBHOOK_RETRY_BASE_SECONDS, WEBHOOK_RETRY_MAX_SECONDS and
+// WEBHOOK_TIMEOUT_SECONDS.
+func WebhookRetries() WebhookRetryPolicy {
+	return WebhookRetryPolicy{
+		MaxAttempts: positiveInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		BaseDelay:   time.Duration(positiveInt("WEBHOOK_RETRY_BASE_SECONDS", 10)) * time.Second,
+		MaxDelay:    time.Duration(positiveInt("WEBHOOK_RETRY_MAX_SECONDS", 3600)) * time.Second,
+		Timeout:     time.Duration(positiveInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+	}
+}
+
+func positiveInt(key string, fallback int) int {
+	value, err := strconv.Atoi(utils.Getenv(key, ""))
+	if err != nil || value < 1 {
+		return fallback
+	}
+	return value
+}
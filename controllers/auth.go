@@ -6,6 +6,7 @@ import (
 	"backend-vercel-phone-review/utils"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -21,6 +22,54 @@ type Claims struct {
 	jwt.StandardClaims
 }
 
+// currentUserID returns the ID of the user authenticated by JWTAuthMiddleware.
+func currentUserID(c *gin.Context) (uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := userID.(uint)
+	return id, ok
+}
+
+// requireAuthor lets the authenticated user change content written by
+// authorID when they wrote it or are a moderator or admin. It writes the
+// error response itself and returns false otherwise.
+func requireAuthor(c *gin.Context, authorID uint, what string) bool {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return false
+	}
+	if userID == authorID {
+		return true
+	}
+
+	var user models.User
+	if err := config.DB.Select("id", "role").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if user.Role == models.RoleModerator || user.Role == models.RoleAdmin {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "you can only change your own " + what})
+	return false
+}
+
+// pathID parses the numeric ID in the path parameter param. It writes a bad
+// request response naming the ID and returns false when the value is not
+// one, so it never reaches a query as raw SQL.
+func pathID(c *gin.Context, param, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + " ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
 // Register godoc
 // @Summary Register a new user
 // @Description Register a new user
@@ -33,11 +82,13 @@ type Claims struct {
 func Register(c *gin.Context) {
 	var input models.User
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
 		return
 	}
 
 	input.Password = utils.HashPassword(input.Password)
+	input.Role = models.RoleUser
+	input.Trusted = false
 
 	if err := config.DB.Create(&input).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -61,7 +112,7 @@ func Login(c *gin.Context) {
 	var user models.User
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
 		return
 	}
 
@@ -108,13 +159,17 @@ func Login(c *gin.Context) {
 // @Param id path int true "User ID"
 // @Param password body models.ChangePasswordRequest true "Password"
 // @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
 // @Router /auth/change-password/{id} [put]
 func ChangePassword(c *gin.Context) {
-	userID := c.Param("id")
+	userID, ok := pathID(c, "id", "user")
+	if !ok {
+		return
+	}
 	var request models.ChangePasswordRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
 		return
 	}
 
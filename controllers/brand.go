@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetBrands godoc
+// @Summary Get all brands
+// @Description Get all brands with their number of phones and the review count and average rating over all their phones
+// @Tags brands
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.BrandStats
+// @Router /brands [get]
+func GetBrands(c *gin.Context) {
+	brands := []models.BrandStats{}
+	if err := brandStats(config.DB).Order("brands.name ASC").Scan(&brands).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, brands)
+}
+
+// CreateBrand godoc
+// @Summary Create a brand
+// @Description Create a brand ahead of its phones. Phones create their brand themselves otherwise. Admins only.
+// @Tags brands
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param brand body models.BrandRequest true "Brand"
+// @Success 201 {object} models.Brand
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]string
+// @Router /brands [post]
+func CreateBrand(c *gin.Context) {
+	var input models.BrandRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	brand := models.Brand{Slug: utils.Slugify(input.Name)}
+	if brand.Slug == "" {
+		utils.RespondValidationError(c, []utils.FieldError{{Field: "name", Message: "must contain letters or digits"}})
+		return
+	}
+	saveBrand(c, &brand, input, http.StatusCreated)
+}
+
+// GetBrand godoc
+// @Summary Get a brand
+// @Description Get a brand with the rating aggregates over all its phones, overall and per sub-rating dimension
+// @Tags brands
+// @Accept json
+// @Produce json
+// @Param slug path string true "Brand slug, e.g. samsung"
+// @Success 200 {object} models.BrandStats
+// @Failure 404 {object} map[string]string
+// @Router /brands/{slug} [get]
+func GetBrand(c *gin.Context) {
+	var stats models.BrandStats
+	result := brandStats(config.DB).Where("brands.slug = ?", c.Param("slug")).Scan(&stats)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "brand not found"})
+		return
+	}
+
+	var dimensions []models.DimensionStats
+	if err := config.DB.Model(&models.SubRating{}).
+		Select("sub_ratings.dimension, AVG(sub_ratings.score) AS average, COUNT(*) AS count").
+		Joins("JOIN reviews ON reviews.id = sub_ratings.review_id AND reviews.deleted_at IS NULL").
+		Joins("JOIN phones ON phones.id = reviews.phone_id AND phones.deleted_at IS NULL").
+		Where("phones.brand_id = ? AND reviews.status = ?", stats.ID, models.ReviewStatusPublished).
+		Group("sub_ratings.dimension").
+		Scan(&dimensions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Report every configured dimension, even those nobody has scored yet
+	byDimension := make(map[string]models.DimensionStats)
+	for _, dimension := range dimensions {
+		byDimension[dimension.Dimension] = dimension
+	}
+	for _, dimension := range config.RatingDimensions() {
+		entry, ok := byDimension[dimension]
+		if !ok {
+			entry = models.DimensionStats{Dimension: dimension}
+		}
+		stats.Dimensions = append(stats.Dimensions, entry)
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// UpdateBrand godoc
+// @Summary Update a brand
+// @Description Update the name, logo, country or description of a brand. A new name is copied to its phones, while the slug stays the same. Admins only.
+// @Tags brands
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param slug path string true "Brand slug"
+// @Param brand body models.BrandRequest true "Brand"
+// @Success 200 {object} models.Brand
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /brands/{slug} [put]
+func UpdateBrand(c *gin.Context) {
+	var input models.BrandRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	var brand models.Brand
+	if err := config.DB.Where("slug = ?", c.Param("slug")).First(&brand).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "brand not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	saveBrand(c, &brand, input, http.StatusOK)
+}
+
+// GetBrandPhones godoc
+// @Summary Get the phones of a brand
+// @Description Get the phones of a brand, optionally filtered and sorted by numeric specs like GET /phones
+// @Tags brands
+// @Accept json
+// @Produce json
+// @Param slug path string true "Brand slug"
+// @Param min[spec_key] query string false "Only phones whose spec is at least this value"
+// @Param max[spec_key] query string false "Only phones whose spec is at most this value"
+// @Param sort_spec query string false "Sort by this numeric spec, phones without a value last"
+// @Param order query string false "Sort order for sort_spec: asc or desc (default desc)"
+// @Success 200 {array} models.Phone
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /brands/{slug}/phones [get]
+func GetBrandPhones(c *gin.Context) {
+	var brand models.Brand
+	if err := config.DB.Where("slug = ?", c.Param("slug")).First(&brand).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "brand not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	query, ok := filterPhonesBySpecs(c, config.DB.Model(&models.Phone{}).Where("phones.brand_id = ?", brand.ID))
+	if !ok {
+		return
+	}
+
+	phones := []models.Phone{}
+	if err := query.Preload("Features").Preload("Reviews", publishedReviews).Find(&phones).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, phones)
+}
+
+// brandStats selects every brand with the counts and average rating of
+// the published reviews of its phones.
+func brandStats(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Brand{}).
+		Select("brands.*, COUNT(DISTINCT phones.id) AS phone_count, COUNT(reviews.id) AS review_count, COALESCE(AVG(reviews.rating), 0) AS average_rating").
+		Joins("LEFT JOIN phones ON phones.brand_id = brands.id AND phones.deleted_at IS NULL").
+		Joins("LEFT JOIN reviews ON reviews.phone_id = phones.id AND reviews.deleted_at IS NULL AND reviews.status = ?", models.ReviewStatusPublished).
+		Group("brands.id")
+}
+
+// saveBrand applies a request to a new or existing brand, copying its name
+// to its phones.
+func saveBrand(c *gin.Context, brand *models.Brand, input models.BrandRequest, status int) {
+	name := strings.Join(strings.Fields(input.Name), " ")
+
+	// Another brand answering to the name would make phones ambiguous
+	var count int64
+	if err := config.DB.Model(&models.Brand{}).
+		Where("id <> ? AND (slug IN ? OR LOWER(name) = ?)", brand.ID, []string{brand.Slug, utils.Slugify(name)}, strings.ToLower(name)).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "a brand with this name already exists"})
+		return
+	}
+
+	brand.Name = name
+	brand.LogoURL = input.LogoURL
+	brand.Country = strings.TrimSpace(input.Country)
+	brand.Description = strings.TrimSpace(input.Description)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(brand).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Phone{}).Where("brand_id = ?", brand.ID).Update("brand", brand.Name).Error
+	})
+	if err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			c.JSON(http.StatusConflict, gin.H{"error": "a brand with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, brand)
+}
+
+// resolveBrand finds the brand a phone's free text brand names, by slug or
+// by name ignoring case, and creates it when there is none. The phone is
+// then linked to it under the brand's own spelling.
+func resolveBrand(tx *gorm.DB, phone *models.Phone) error {
+	name := strings.Join(strings.Fields(phone.Brand), " ")
+	slug := utils.Slugify(name)
+	find := func(brand *models.Brand) error {
+		return tx.Where("slug = ? OR LOWER(name) = ?", slug, strings.ToLower(name)).Order("id ASC").First(brand).Error
+	}
+
+	var brand models.Brand
+	err := find(&brand)
+	if err == gorm.ErrRecordNotFound {
+		// A concurrent request may create the same brand first
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Brand{Slug: slug, Name: name}).Error; err != nil {
+			return err
+		}
+		err = find(&brand)
+	}
+	if err != nil {
+		return err
+	}
+
+	phone.BrandID = brand.ID
+	phone.Brand = brand.Name
+	return nil
+}
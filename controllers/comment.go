@@ -3,10 +3,13 @@ package controllers
 import (
 	"backend-vercel-phone-review/config"
 	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateComment godoc
@@ -19,48 +22,195 @@ import (
 // @Security ApiKeyAuth
 // @Param comment body models.Comment true "Comment"
 // @Success 200 {object} models.Comment
+// @Failure 404 {object} map[string]string
 // @Router /comments [post]
 func CreateComment(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
 	var comment models.Comment
 	if err := c.ShouldBindJSON(&comment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.Scopes(publishedReviews).First(&review, comment.ReviewID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	comment.Depth = 0
+	if comment.ParentID != nil {
+		var parent models.Comment
+		if err := config.DB.Scopes(publishedComments).First(&parent, *comment.ParentID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "parent comment not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		if parent.ReviewID != comment.ReviewID {
+			utils.RespondValidationError(c, []utils.FieldError{{Field: "parent_id", Message: "must be a comment on the same review"}})
+			return
+		}
+		if maxDepth := config.CommentMaxDepth(); parent.Depth+1 >= maxDepth {
+			utils.RespondValidationError(c, []utils.FieldError{{Field: "parent_id", Message: fmt.Sprintf("replies cannot be nested more than %d levels deep", maxDepth-1)}})
+			return
+		}
+		comment.Depth = parent.Depth + 1
+	}
+
+	comment.ID = 0
+	comment.UserID = userID
+	comment.Status = models.CommentStatusPublished
+	comment.Mentions = nil
+	if !screenComment(c, &comment) {
+		return
+	}
+
+	err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&comment).Error; err != nil {
+			return err
+		}
+		mentioned, err := saveMentions(tx, &comment)
+		if err != nil {
+			return err
+		}
+		return notifyComment(tx, comment, mentioned)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := config.DB.Create(&comment).Error; err != nil {
+	if err := renderComment(&comment); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	publishComment(comment)
 	c.JSON(http.StatusOK, comment)
 }
 
 // GetComments godoc
 // @Summary Get comments by review ID
-// @Description Get comments by review ID
+// @Description Get a page of top-level comments on a published review with all their replies, either as a flat list in thread order with each comment's depth or as a tree. A removed comment that still has visible replies stays in place as a placeholder without its content, so a page can hold fewer top-level comments than the limit.
 // @Tags comments
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "JWT Authorization header"
 // @Security ApiKeyAuth
 // @Param review_id path int true "Review ID"
-// @Success 200 {object} []models.Comment
+// @Param view query string false "flat (default) or tree"
+// @Param sort query string false "Sort order of top-level comments: oldest (default) or newest"
+// @Param limit query int false "Number of top-level comments, at most 100"
+// @Param cursor query string false "next_cursor of the previous page, the X-Next-Cursor header, or more_comments_cursor of a review"
+// @Param format query string false "page to get a models.CommentPage instead of an array"
+// @Success 200 {object} []models.CommentThread "The next cursor is in the X-Next-Cursor header"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Router /comments/{review_id} [get]
 func GetComments(c *gin.Context) {
-	reviewID := c.Param("review_id")
+	reviewID, ok := pathID(c, "review_id", "review")
+	if !ok {
+		return
+	}
 
-	var comments []models.Comment
-	if err := config.DB.Where("review_id = ?", reviewID).Find(&comments).Error; err != nil {
+	view := c.DefaultQuery("view", "flat")
+	if view != "flat" && view != "tree" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid view"})
+		return
+	}
+
+	p, ok := parsePage(c, sortOldest, sortNewest)
+	if !ok {
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.Scopes(publishedReviews).Select("id").First(&review, reviewID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Removed comments are loaded too, so their published replies can
+	// still be shown below a placeholder
+	var roots []models.Comment
+	query := config.DB.Unscoped().Where("comments.review_id = ? AND comments.parent_id IS NULL", reviewID)
+	if err := keyset(query, "comments", p).Find(&roots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.CommentPage{}
+	if len(roots) > p.Limit {
+		roots = roots[:p.Limit]
+		last := roots[p.Limit-1]
+		response.NextCursor = utils.EncodeCursor(utils.Cursor{ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+
+	replies, err := loadReplies(roots)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, comments)
+	comments := append(roots, replies...)
+	if err := renderMentions(comments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	threads := pruneRemovedComments(buildCommentThreads(comments))
+	if view == "tree" {
+		response.Items = make([]models.CommentThread, 0, len(threads))
+		for _, thread := range threads {
+			response.Items = append(response.Items, *thread)
+		}
+	} else {
+		response.Items = flattenCommentThreads(threads)
+	}
+
+	respondPage(c, response, response.Items, response.NextCursor)
+}
+
+// loadReplies fetches every reply below the given comments, level by level,
+// oldest first within each level. Unpublished and deleted replies are
+// included, for pruneRemovedComments to sort out.
+func loadReplies(parents []models.Comment) ([]models.Comment, error) {
+	var replies []models.Comment
+	for len(parents) > 0 {
+		ids := make([]uint, len(parents))
+		for i, parent := range parents {
+			ids[i] = parent.ID
+		}
+
+		var level []models.Comment
+		if err := config.DB.Unscoped().Where("parent_id IN ?", ids).Order("created_at ASC, id ASC").Find(&level).Error; err != nil {
+			return nil, err
+		}
+		replies = append(replies, level...)
+		parents = level
+	}
+	return replies, nil
 }
 
 // UpdateComment godoc
 // @Summary Update a comment
-// @Description Update a comment
+// @Description Update a comment. Only its author, a moderator or an admin may do so.
 // @Tags comments
 // @Accept json
 // @Produce json
@@ -71,13 +221,18 @@ func GetComments(c *gin.Context) {
 // @Param id path int true "Comment ID"
 // @Param comment body models.Comment true "Comment"
 // @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /comments/{id} [put]
 func UpdateComment(c *gin.Context) {
-	commentID := c.Param("id")
+	commentID, ok := pathID(c, "id", "comment")
+	if !ok {
+		return
+	}
 
 	var updatedComment models.Comment
 	if err := c.ShouldBindJSON(&updatedComment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
 		return
 	}
 
@@ -92,9 +247,31 @@ func UpdateComment(c *gin.Context) {
 		return
 	}
 
+	if !requireAuthor(c, existingComment.UserID, "comments") {
+		return
+	}
+
 	existingComment.Content = updatedComment.Content
+	if !screenComment(c, &existingComment) {
+		return
+	}
 
-	if err := config.DB.Save(&existingComment).Error; err != nil {
+	err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&existingComment).Error; err != nil {
+			return err
+		}
+		mentioned, err := saveMentions(tx, &existingComment)
+		if err != nil {
+			return err
+		}
+		return notifyMentions(tx, existingComment, mentioned)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := renderComment(&existingComment); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -104,7 +281,7 @@ func UpdateComment(c *gin.Context) {
 
 // DeleteComment godoc
 // @Summary Delete a comment
-// @Description Delete a comment
+// @Description Delete a comment. Only its author, a moderator or an admin may do so.
 // @Tags comments
 // @Accept json
 // @Produce json
@@ -112,9 +289,14 @@ func UpdateComment(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Param id path int true "Comment ID"
 // @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /comments/{id} [delete]
 func DeleteComment(c *gin.Context) {
-	commentID := c.Param("id")
+	commentID, ok := pathID(c, "id", "comment")
+	if !ok {
+		return
+	}
 
 	var existingComment models.Comment
 	result := config.DB.First(&existingComment, commentID)
@@ -127,6 +309,10 @@ func DeleteComment(c *gin.Context) {
 		return
 	}
 
+	if !requireAuthor(c, existingComment.UserID, "comments") {
+		return
+	}
+
 	if err := config.DB.Delete(&existingComment).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -134,3 +320,135 @@ func DeleteComment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "comment deleted successfully"})
 }
+
+// publishedComments limits a comment query to comments visible to the public.
+func publishedComments(db *gorm.DB) *gorm.DB {
+	return db.Where("comments.status = ?", models.CommentStatusPublished)
+}
+
+// buildCommentThreads nests comments under their parents and counts direct
+// replies. Comments whose parent is not in the list are kept at the top
+// level in the order given.
+func buildCommentThreads(comments []models.Comment) []*models.CommentThread {
+	threads := make(map[uint]*models.CommentThread, len(comments))
+	for _, comment := range comments {
+		threads[comment.ID] = &models.CommentThread{Comment: comment}
+	}
+
+	roots := []*models.CommentThread{}
+	for _, comment := range comments {
+		thread := threads[comment.ID]
+		if comment.ParentID != nil {
+			if parent, ok := threads[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, thread)
+				parent.ReplyCount++
+				continue
+			}
+		}
+		roots = append(roots, thread)
+	}
+
+	return roots
+}
+
+// pruneRemovedComments drops unpublished and deleted comments from the
+// threads, unless published replies hang below them. Those stay in place
+// as placeholders stripped of their author and content.
+func pruneRemovedComments(threads []*models.CommentThread) []*models.CommentThread {
+	kept := []*models.CommentThread{}
+	for _, thread := range threads {
+		thread.Replies = pruneRemovedComments(thread.Replies)
+		thread.ReplyCount = len(thread.Replies)
+		if thread.Status == models.CommentStatusPublished && !thread.DeletedAt.Valid {
+			kept = append(kept, thread)
+			continue
+		}
+		if len(thread.Replies) == 0 {
+			continue
+		}
+
+		thread.Comment = models.Comment{
+			Model:    thread.Model,
+			ReviewID: thread.ReviewID,
+			ParentID: thread.ParentID,
+			Depth:    thread.Depth,
+			Mentions: []models.Mention{},
+		}
+		thread.Removed = true
+		kept = append(kept, thread)
+	}
+	return kept
+}
+
+// flattenCommentThreads lists threads depth first, so every reply follows
+// its parent. Replies are dropped from the entries themselves.
+func flattenCommentThreads(threads []*models.CommentThread) []models.CommentThread {
+	flat := []models.CommentThread{}
+	var walk func([]*models.CommentThread)
+	walk = func(threads []*models.CommentThread) {
+		for _, thread := range threads {
+			entry := *thread
+			entry.Replies = nil
+			flat = append(flat, entry)
+			walk(thread.Replies)
+		}
+	}
+	walk(threads)
+	return flat
+}
+
+// loadCommentPreviews fills each review's comments with its oldest limit
+// published top-level comments, setting a cursor for GetComments when the
+// review has more.
+func loadCommentPreviews(reviews []models.Review, limit int) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(reviews))
+	for i := range reviews {
+		ids[i] = reviews[i].ID
+		reviews[i].Comments = []models.Comment{}
+	}
+	if limit == 0 {
+		return nil
+	}
+
+	// Fetch one extra comment per review to learn whether there are more.
+	// A comment makes the cut when fewer than limit+1 comments on its
+	// review come before it; window functions would need MySQL 8.
+	var comments []models.Comment
+	if err := config.DB.Raw(`SELECT comments.* FROM comments
+		WHERE comments.review_id IN ? AND comments.parent_id IS NULL AND comments.status = ? AND comments.deleted_at IS NULL
+		AND (SELECT COUNT(*) FROM comments earlier
+			WHERE earlier.review_id = comments.review_id AND earlier.parent_id IS NULL
+			AND earlier.status = comments.status AND earlier.deleted_at IS NULL
+			AND (earlier.created_at < comments.created_at OR (earlier.created_at = comments.created_at AND earlier.id < comments.id))
+		) <= ?
+		ORDER BY comments.review_id, comments.created_at ASC, comments.id ASC`, ids, models.CommentStatusPublished, limit).
+		Scan(&comments).Error; err != nil {
+		return err
+	}
+
+	byReview := make(map[uint]*models.Review, len(reviews))
+	for i := range reviews {
+		byReview[reviews[i].ID] = &reviews[i]
+	}
+	for _, comment := range comments {
+		review := byReview[comment.ReviewID]
+		if len(review.Comments) == limit {
+			last := review.Comments[limit-1]
+			review.MoreComments = utils.EncodeCursor(utils.Cursor{ID: last.ID, CreatedAt: last.CreatedAt})
+			continue
+		}
+		review.Comments = append(review.Comments, comment)
+	}
+
+	for i := range reviews {
+		if err := renderMentions(reviews[i].Comments); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	minComparePhones = 2
+	maxComparePhones = 4
+)
+
+// ComparePhones godoc
+// @Summary Compare phones side by side
+// @Description Line up the specs of two to four phones, grouped like on the phone page, marking the rows that differ and the best value of specs that rank their values. Includes each phone's rating statistics and the phone that comes out ahead in every spec group and rating.
+// @Tags phones
+// @Accept json
+// @Produce json
+// @Param ids query string true "Comma separated phone IDs, such as 1,2,3"
+// @Success 200 {object} models.PhoneComparison
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /phones/compare [get]
+func ComparePhones(c *gin.Context) {
+	ids, err := parseCompareIDs(c.Query("ids"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var found []models.Phone
+	if err := config.DB.Preload("Features").Where("id IN ?", ids).Find(&found).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	comparison := models.PhoneComparison{}
+	var missing []string
+	for _, id := range ids {
+		i := slices.IndexFunc(found, func(phone models.Phone) bool { return phone.ID == id })
+		if i < 0 {
+			missing = append(missing, strconv.FormatUint(uint64(id), 10))
+			continue
+		}
+		phone := found[i]
+		phone.Reviews = []models.Review{}
+		comparison.Phones = append(comparison.Phones, phone)
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "phones not found: " + strings.Join(missing, ", ")})
+		return
+	}
+
+	comparison.Specs, err = compareSpecs(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, id := range ids {
+		stats, err := phoneStats(config.DB, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		comparison.Ratings = append(comparison.Ratings, stats)
+	}
+
+	comparison.Winners = compareWinners(comparison)
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// parseCompareIDs reads the distinct phone IDs of a comparison, keeping
+// their order.
+func parseCompareIDs(raw string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid phone ID %q", part)
+		}
+		if !slices.Contains(ids, uint(id)) {
+			ids = append(ids, uint(id))
+		}
+	}
+
+	if len(ids) < minComparePhones || len(ids) > maxComparePhones {
+		return nil, fmt.Errorf("ids must list between %d and %d different phones", minComparePhones, maxComparePhones)
+	}
+	return ids, nil
+}
+
+// compareSpecs builds the spec matrix of the phones: a row for every spec
+// at least one of them has, with a value per phone in the order of ids.
+func compareSpecs(ids []uint) ([]models.CompareGroup, error) {
+	definitions, err := loadSpecDefinitions(config.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []models.SpecValue
+	if err := config.DB.Where("phone_id IN ?", ids).Find(&values).Error; err != nil {
+		return nil, err
+	}
+	type cell struct {
+		definitionID uint
+		phoneID      uint
+	}
+	byCell := make(map[cell]models.SpecValue, len(values))
+	for _, value := range values {
+		byCell[cell{value.SpecDefinitionID, value.PhoneID}] = value
+	}
+
+	groups := []models.CompareGroup{}
+	for _, definition := range definitions {
+		row := models.CompareRow{
+			Key:      definition.Key,
+			Label:    definition.Label,
+			DataType: definition.DataType,
+			Unit:     definition.Unit,
+		}
+
+		var ranks []*float64
+		present := 0
+		for _, id := range ids {
+			value, ok := byCell[cell{definition.ID, id}]
+			entry := models.CompareValue{PhoneID: id}
+			if ok {
+				entry.Value = specValue(definition, value)
+				present++
+			}
+			row.Values = append(row.Values, entry)
+			ranks = append(ranks, specRank(definition, value, ok))
+		}
+		if present == 0 {
+			continue
+		}
+
+		for _, entry := range row.Values[1:] {
+			row.Differs = row.Differs || fmt.Sprint(entry.Value) != fmt.Sprint(row.Values[0].Value)
+		}
+		if row.Differs {
+			markBest(row.Values, ranks)
+		}
+
+		if len(groups) == 0 || groups[len(groups)-1].Group != definition.Group {
+			groups = append(groups, models.CompareGroup{Group: definition.Group})
+		}
+		last := &groups[len(groups)-1]
+		last.Rows = append(last.Rows, row)
+	}
+	return groups, nil
+}
+
+// specRank turns a value of a spec that ranks its values into a number
+// where more is better. It is nil for missing values and specs that do not
+// rank them.
+func specRank(definition models.SpecDefinition, value models.SpecValue, ok bool) *float64 {
+	if !ok || definition.Better == "" {
+		return nil
+	}
+
+	var rank float64
+	switch {
+	case value.Number != nil:
+		rank = *value.Number
+	case value.Bool != nil && *value.Bool:
+		rank = 1
+	case value.Bool != nil:
+		rank = 0
+	default:
+		return nil
+	}
+	if definition.Better == models.SpecLowerIsBetter {
+		rank = -rank
+	}
+	return &rank
+}
+
+// markBest flags the values with the highest rank, as long as at least two
+// phones have a ranked value.
+func markBest(values []models.CompareValue, ranks []*float64) {
+	var best *float64
+	ranked := 0
+	for _, rank := range ranks {
+		if rank == nil {
+			continue
+		}
+		ranked++
+		if best == nil || *rank > *best {
+			best = rank
+		}
+	}
+	if ranked < 2 {
+		return
+	}
+
+	for i, rank := range ranks {
+		values[i].Best = rank != nil && *rank == *best
+	}
+}
+
+// compareWinners sums up who comes out ahead: per spec group, the phone
+// with the best value in the most ranked rows, then the best rated phone
+// overall and per sub-rating dimension.
+func compareWinners(comparison models.PhoneComparison) []models.CategoryWinner {
+	names := make(map[uint]string, len(comparison.Phones))
+	for _, phone := range comparison.Phones {
+		names[phone.ID] = phone.Brand + " " + phone.Name
+	}
+
+	winners := []models.CategoryWinner{}
+	for _, group := range comparison.Specs {
+		wins := make(map[uint]float64)
+		contested := 0
+		for _, row := range group.Rows {
+			counted := false
+			for _, value := range row.Values {
+				if value.Best {
+					wins[value.PhoneID]++
+					counted = true
+				}
+			}
+			if counted {
+				contested++
+			}
+		}
+		if contested == 0 {
+			continue
+		}
+
+		winner := models.CategoryWinner{Category: group.Group}
+		if id, ok := leader(wins); ok {
+			winner.PhoneID = &id
+			winner.Summary = fmt.Sprintf("%s leads %d of %d ranked %s specs", names[id], int(wins[id]), contested, group.Group)
+		} else {
+			winner.Summary = fmt.Sprintf("No clear winner across %d ranked %s specs", contested, group.Group)
+		}
+		winners = append(winners, winner)
+	}
+
+	averages := make(map[uint]float64)
+	for _, stats := range comparison.Ratings {
+		if stats.ReviewCount > 0 {
+			averages[stats.PhoneID] = stats.AverageRating
+		}
+	}
+	if winner, ok := ratingWinner("Overall rating", averages, names); ok {
+		winners = append(winners, winner)
+	}
+
+	for _, dimension := range config.RatingDimensions() {
+		averages := make(map[uint]float64)
+		for _, stats := range comparison.Ratings {
+			for _, entry := range stats.Dimensions {
+				if entry.Dimension == dimension && entry.Count > 0 {
+					averages[stats.PhoneID] = entry.Average
+				}
+			}
+		}
+		category := strings.ToUpper(dimension[:1]) + dimension[1:] + " rating"
+		if winner, ok := ratingWinner(category, averages, names); ok {
+			winners = append(winners, winner)
+		}
+	}
+
+	return winners
+}
+
+// ratingWinner names the phone with the highest average of a category,
+// provided at least two phones were rated in it.
+func ratingWinner(category string, averages map[uint]float64, names map[uint]string) (models.CategoryWinner, bool) {
+	if len(averages) < 2 {
+		return models.CategoryWinner{}, false
+	}
+
+	winner := models.CategoryWinner{Category: category}
+	if id, ok := leader(averages); ok {
+		winner.PhoneID = &id
+		winner.Summary = fmt.Sprintf("%s is rated highest with %.1f out of %d", names[id], averages[id], utils.MaxRating)
+	} else {
+		winner.Summary = "No clear winner: the top phones are rated the same"
+	}
+	return winner, true
+}
+
+// leader returns the key with the highest score, unless several share it.
+func leader(scores map[uint]float64) (uint, bool) {
+	var best uint
+	tied := false
+	for id, score := range scores {
+		switch {
+		case best == 0 || score > scores[best]:
+			best, tied = id, false
+		case score == scores[best]:
+			tied = true
+		}
+	}
+	return best, best != 0 && !tied
+}
@@ -0,0 +1,713 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/mailer"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/outbox"
+	"backend-vercel-phone-review/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// digestBatchSize bounds how many due digests one scheduling pass queues.
+	digestBatchSize = 200
+	// digestMaxItems bounds each section of a digest.
+	digestMaxItems = 20
+	// digestExcerptLength is how many characters of a review or comment a
+	// digest quotes.
+	digestExcerptLength = 200
+
+	// digestConfirmLifetime is how long the link that confirms a digest
+	// address works.
+	digestConfirmLifetime = 7 * 24 * time.Hour
+
+	unsubscribeTokenPrefix = "digest-unsubscribe:"
+	confirmTokenPrefix     = "digest-confirm:"
+)
+
+// digestJob is the outbox payload of one digest email, covering activity
+// from Since up to Until.
+type digestJob struct {
+	SubscriptionID uint      `json:"subscription_id"`
+	Since          time.Time `json:"since"`
+	Until          time.Time `json:"until"`
+}
+
+// digestConfirmJob is the outbox payload of the email that asks to confirm
+// the address of a digest subscription.
+type digestConfirmJob struct {
+	SubscriptionID uint      `json:"subscription_id"`
+	Email          string    `json:"email"`
+	RequestedAt    time.Time `json:"requested_at"`
+}
+
+// digestConfirmEmail is the data of the digest_confirm templates.
+type digestConfirmEmail struct {
+	Username   string
+	Email      string
+	Frequency  string
+	ConfirmURL string
+}
+
+// digestLinkPage is what the confirm and unsubscribe links in email open:
+// a button that repeats the request as a POST, so mail scanners and link
+// previews that follow every link change nothing.
+var digestLinkPage = template.Must(template.New("digest-link").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; color: #222; max-width: 600px;">
+<h1 style="font-size: 20px;">{{.Title}}</h1>
+<form method="post" action="{{.Action}}"><button type="submit">{{.Button}}</button></form>
+</body>
+</html>
+`))
+
+// digestEmail is the data of the digest templates.
+type digestEmail struct {
+	Username       string
+	Frequency      string
+	Since          time.Time
+	Until          time.Time
+	Reviews        []digestReview
+	Replies        []digestReply
+	UnsubscribeURL string
+}
+
+type digestReview struct {
+	Phone   string
+	Author  string
+	Rating  int
+	Excerpt string
+}
+
+type digestReply struct {
+	Phone     string
+	Author    string
+	OnComment bool
+	Excerpt   string
+}
+
+// GetDigestSubscription godoc
+// @Summary Get my email digest subscription
+// @Description Get the authenticated user's email digest subscription
+// @Tags digests
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DigestSubscription
+// @Failure 404 {object} map[string]string
+// @Router /digest [get]
+func GetDigestSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var subscription models.DigestSubscription
+	if err := config.DB.Where("user_id = ?", userID).First(&subscription).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not subscribed to the digest"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UpdateDigestSubscription godoc
+// @Summary Subscribe to the email digest
+// @Description Opt in to a daily or weekly email summing up new reviews on followed phones and replies to your reviews and comments, or change the address or frequency of an existing subscription. Changing the frequency restarts the schedule. A new or changed address gets an email with a link to confirm it, and digests start once it is followed; saving an unconfirmed address again sends a new link.
+// @Tags digests
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param subscription body models.DigestRequest true "Digest subscription"
+// @Success 200 {object} models.DigestSubscription
+// @Failure 400 {object} map[string]interface{}
+// @Router /digest [put]
+func UpdateDigestSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var input models.DigestRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	var subscription models.DigestSubscription
+	err := config.DB.Where("user_id = ?", userID).First(&subscription).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err == gorm.ErrRecordNotFound || subscription.Frequency != input.Frequency {
+		now := time.Now()
+		subscription.LastDigestAt = now
+		subscription.NextDigestAt = now.Add(digestPeriod(input.Frequency))
+	}
+	if subscription.Email != input.Email {
+		subscription.ConfirmedAt = nil
+	}
+	subscription.UserID = userID
+	subscription.Email = input.Email
+	subscription.Frequency = input.Frequency
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&subscription).Error; err != nil {
+			return err
+		}
+		if subscription.ConfirmedAt != nil {
+			return nil
+		}
+		return outbox.Enqueue(tx, jobDigestConfirm, digestConfirmJob{
+			SubscriptionID: subscription.ID,
+			Email:          subscription.Email,
+			RequestedAt:    time.Now(),
+		}, outbox.Options{})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteDigestSubscription godoc
+// @Summary Unsubscribe from the email digest
+// @Description Stop the authenticated user's email digest
+// @Tags digests
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]string
+// @Router /digest [delete]
+func DeleteDigestSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	unsubscribeDigest(c, userID)
+}
+
+// ConfirmDigestPage godoc
+// @Summary Open the confirmation link of an email digest
+// @Description Show a page with a button that confirms the digest address, for the link in the confirmation email. Opening the link changes nothing.
+// @Tags digests
+// @Produce html
+// @Param token query string true "Confirmation token"
+// @Success 200 {string} string "HTML page"
+// @Failure 400 {object} map[string]string
+// @Router /digest/confirm [get]
+func ConfirmDigestPage(c *gin.Context) {
+	if _, _, ok := verifyConfirmToken(c); !ok {
+		return
+	}
+	renderDigestLinkPage(c, "Confirm your Phone Review digest", "Confirm")
+}
+
+// ConfirmDigest godoc
+// @Summary Confirm the address of an email digest
+// @Description Start the email digest with the signed token from the confirmation email. Needs no login.
+// @Tags digests
+// @Produce json
+// @Param token query string true "Confirmation token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /digest/confirm [post]
+func ConfirmDigest(c *gin.Context) {
+	userID, email, ok := verifyConfirmToken(c)
+	if !ok {
+		return
+	}
+
+	var subscription models.DigestSubscription
+	if err := config.DB.Where("user_id = ? AND email = ?", userID, email).First(&subscription).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no digest subscription for this address"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if subscription.ConfirmedAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&subscription).Updates(map[string]interface{}{
+			"confirmed_at":   now,
+			"last_digest_at": now,
+			"next_digest_at": now.Add(digestPeriod(subscription.Frequency)),
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "digest confirmed"})
+}
+
+// UnsubscribeDigestPage godoc
+// @Summary Open the unsubscribe link of an email digest
+// @Description Show a page with a button that stops the digest, for the unsubscribe link of a digest email. Opening the link changes nothing.
+// @Tags digests
+// @Produce html
+// @Param token query string true "Unsubscribe token"
+// @Success 200 {string} string "HTML page"
+// @Failure 400 {object} map[string]string
+// @Router /digest/unsubscribe [get]
+func UnsubscribeDigestPage(c *gin.Context) {
+	if _, ok := verifyUnsubscribeToken(c); !ok {
+		return
+	}
+	renderDigestLinkPage(c, "Unsubscribe from the Phone Review digest", "Unsubscribe")
+}
+
+// UnsubscribeDigest godoc
+// @Summary Unsubscribe from the email digest with a link
+// @Description Stop an email digest with the signed token from the unsubscribe link of a digest email. Needs no login and supports one-click unsubscribe from mail clients.
+// @Tags digests
+// @Produce json
+// @Param token query string true "Unsubscribe token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /digest/unsubscribe [post]
+func UnsubscribeDigest(c *gin.Context) {
+	userID, ok := verifyUnsubscribeToken(c)
+	if !ok {
+		return
+	}
+
+	unsubscribeDigest(c, userID)
+}
+
+func renderDigestLinkPage(c *gin.Context, title, button string) {
+	var page strings.Builder
+	if err := digestLinkPage.Execute(&page, map[string]string{
+		"Title":  title,
+		"Button": button,
+		"Action": c.Request.URL.RequestURI(),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page.String()))
+}
+
+// verifyMailToken checks the signed token query parameter of a link in an
+// email and returns its value without prefix. It writes the error response
+// itself when the token is invalid.
+func verifyMailToken(c *gin.Context, prefix, what string) (string, bool) {
+	secret, err := config.MailTokenSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", false
+	}
+
+	value, err := utils.VerifyToken(secret, c.Query("token"))
+	if err != nil || !strings.HasPrefix(value, prefix) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + what + " token"})
+		return "", false
+	}
+	return strings.TrimPrefix(value, prefix), true
+}
+
+func verifyUnsubscribeToken(c *gin.Context) (uint, bool) {
+	value, ok := verifyMailToken(c, unsubscribeTokenPrefix, "unsubscribe")
+	if !ok {
+		return 0, false
+	}
+
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid unsubscribe token"})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// verifyConfirmToken reads a token made by confirmURL, which holds the user
+// ID, the expiry and the address to confirm.
+func verifyConfirmToken(c *gin.Context) (uint, string, bool) {
+	value, ok := verifyMailToken(c, confirmTokenPrefix, "confirmation")
+	if !ok {
+		return 0, "", false
+	}
+
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid confirmation token"})
+		return 0, "", false
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid confirmation token"})
+		return 0, "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid confirmation token"})
+		return 0, "", false
+	}
+	if time.Now().Unix() > expires {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confirmation link has expired"})
+		return 0, "", false
+	}
+
+	return uint(userID), parts[2], true
+}
+
+func unsubscribeDigest(c *gin.Context, userID uint) {
+	// Deleted for good so subscribing again does not hit the unique index.
+	if err := config.DB.Unscoped().Where("user_id = ?", userID).Delete(&models.DigestSubscription{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "unsubscribed from the digest"})
+}
+
+// unsubscribeURL is the link in a digest email that stops the digest
+// without logging in.
+func unsubscribeURL(userID uint) (string, error) {
+	secret, err := config.MailTokenSecret()
+	if err != nil {
+		return "", err
+	}
+	token := utils.SignToken(secret, unsubscribeTokenPrefix+strconv.FormatUint(uint64(userID), 10))
+	return config.PublicURL() + "/api/v1/digest/unsubscribe?token=" + url.QueryEscape(token), nil
+}
+
+// confirmURL is the link in the confirmation email that starts a digest to
+// email.
+func confirmURL(userID uint, email string) (string, error) {
+	secret, err := config.MailTokenSecret()
+	if err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(digestConfirmLifetime).Unix()
+	token := utils.SignToken(secret, fmt.Sprintf("%s%d:%d:%s", confirmTokenPrefix, userID, expires, email))
+	return config.PublicURL() + "/api/v1/digest/confirm?token=" + url.QueryEscape(token), nil
+}
+
+func digestPeriod(frequency string) time.Duration {
+	if frequency == models.DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// QueueDueDigests queues a send job for every digest that is due and moves
+// its schedule on. It is run by the cron endpoint and the worker command.
+func QueueDueDigests(ctx context.Context) (int, error) {
+	var due []models.DigestSubscription
+	now := time.Now()
+	if err := config.DB.WithContext(ctx).
+		Where("confirmed_at IS NOT NULL AND next_digest_at <= ?", now).
+		Order("next_digest_at ASC").
+		Limit(digestBatchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, subscription := range due {
+		err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Skip the subscription if another pass got to it first.
+			result := tx.Model(&models.DigestSubscription{}).
+				Where("id = ? AND next_digest_at = ?", subscription.ID, subscription.NextDigestAt).
+				Updates(map[string]interface{}{
+					"last_digest_at": now,
+					"next_digest_at": now.Add(digestPeriod(subscription.Frequency)),
+				})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			if err := outbox.Enqueue(tx, jobDigestSend, digestJob{
+				SubscriptionID: subscription.ID,
+				Since:          subscription.LastDigestAt,
+				Until:          now,
+			}, outbox.Options{Key: fmt.Sprintf("digest:%d:%d", subscription.ID, subscription.NextDigestAt.Unix())}); err != nil {
+				return err
+			}
+			queued++
+			return nil
+		})
+		if err != nil {
+			return queued, err
+		}
+	}
+
+	return queued, nil
+}
+
+// sendDigest is the job handler that emails one digest. Digests with
+// nothing to report are not sent. It runs outside a transaction, and a
+// digest already sent by an earlier run of the job is not sent again.
+func sendDigest(ctx context.Context, db *gorm.DB, raw json.RawMessage) error {
+	var job digestJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	var subscription models.DigestSubscription
+	if err := db.First(&subscription, job.SubscriptionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	if subscription.ConfirmedAt == nil {
+		return nil
+	}
+	if subscription.LastSentAt != nil && !subscription.LastSentAt.Before(job.Until) {
+		return nil
+	}
+
+	var user models.User
+	if err := db.Select("id", "username").First(&user, subscription.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	unsubscribe, err := unsubscribeURL(user.ID)
+	if err != nil {
+		return err
+	}
+	email := digestEmail{
+		Username:       user.Username,
+		Frequency:      subscription.Frequency,
+		Since:          job.Since,
+		Until:          job.Until,
+		UnsubscribeURL: unsubscribe,
+	}
+	if err := loadDigest(db, user.ID, &email); err != nil {
+		return err
+	}
+	if len(email.Reviews) == 0 && len(email.Replies) == 0 {
+		return nil
+	}
+
+	text, html, err := mailer.Render("digest", email)
+	if err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	subject := "Your daily Phone Review digest"
+	if subscription.Frequency == models.DigestWeekly {
+		subject = "Your weekly Phone Review digest"
+	}
+	if err := config.NewMailer().Send(ctx, mailer.Message{
+		From:    config.MailFrom(),
+		To:      subscription.Email,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + email.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}); err != nil {
+		return err
+	}
+
+	return db.Model(&subscription).Update("last_sent_at", time.Now()).Error
+}
+
+// sendDigestConfirmation is the job handler that emails the link that
+// confirms the address of a digest subscription. Nothing is sent when the
+// address has been confirmed or changed since, or when an earlier run of
+// the job sent it already. It runs outside a transaction.
+func sendDigestConfirmation(ctx context.Context, db *gorm.DB, raw json.RawMessage) error {
+	var job digestConfirmJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	var subscription models.DigestSubscription
+	if err := db.First(&subscription, job.SubscriptionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	if subscription.ConfirmedAt != nil || subscription.Email != job.Email {
+		return nil
+	}
+	if subscription.ConfirmationSentAt != nil && !subscription.ConfirmationSentAt.Before(job.RequestedAt) {
+		return nil
+	}
+
+	var user models.User
+	if err := db.Select("id", "username").First(&user, subscription.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	link, err := confirmURL(user.ID, subscription.Email)
+	if err != nil {
+		return err
+	}
+	text, html, err := mailer.Render("digest_confirm", digestConfirmEmail{
+		Username:   user.Username,
+		Email:      subscription.Email,
+		Frequency:  subscription.Frequency,
+		ConfirmURL: link,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	if err := config.NewMailer().Send(ctx, mailer.Message{
+		From:    config.MailFrom(),
+		To:      subscription.Email,
+		Subject: "Confirm your Phone Review digest",
+		Text:    text,
+		HTML:    html,
+	}); err != nil {
+		return err
+	}
+
+	return db.Model(&subscription).Update("confirmation_sent_at", time.Now()).Error
+}
+
+// loadDigest fills in the new published reviews on the phones userID
+// follows and the replies to their reviews and comments, written by others
+// between email.Since and email.Until. Reviews a moderator approved count
+// from their approval, so reviews held in the queue are not missed.
+func loadDigest(tx *gorm.DB, userID uint, email *digestEmail) error {
+	followed := tx.Model(&models.Follow{}).
+		Select("target_id").
+		Where("user_id = ? AND target_type = ?", userID, models.FollowTargetPhone)
+
+	var reviews []models.Review
+	if err := tx.Scopes(publishedReviews).
+		Where("reviews.phone_id IN (?) AND reviews.user_id <> ?", followed, userID).
+		Where("COALESCE(reviews.moderated_at, reviews.created_at) >= ? AND COALESCE(reviews.moderated_at, reviews.created_at) < ?", email.Since, email.Until).
+		Order("COALESCE(reviews.moderated_at, reviews.created_at) ASC").
+		Limit(digestMaxItems).
+		Find(&reviews).Error; err != nil {
+		return err
+	}
+
+	myReviews := tx.Model(&models.Review{}).Select("id").Where("user_id = ?", userID)
+	myComments := tx.Model(&models.Comment{}).Select("id").Where("user_id = ?", userID)
+
+	var replies []models.Comment
+	if err := tx.Scopes(publishedComments).
+		Where("comments.user_id <> ?", userID).
+		Where("(comments.parent_id IS NULL AND comments.review_id IN (?)) OR comments.parent_id IN (?)", myReviews, myComments).
+		Where("comments.created_at >= ? AND comments.created_at < ?", email.Since, email.Until).
+		Order("comments.created_at ASC").
+		Limit(digestMaxItems).
+		Find(&replies).Error; err != nil {
+		return err
+	}
+
+	reviewIDs := make([]uint, 0, len(replies))
+	authorIDs := make([]uint, 0, len(reviews)+len(replies))
+	for _, review := range reviews {
+		authorIDs = append(authorIDs, review.UserID)
+	}
+	for _, reply := range replies {
+		reviewIDs = append(reviewIDs, reply.ReviewID)
+		authorIDs = append(authorIDs, reply.UserID)
+	}
+
+	var repliedTo []models.Review
+	if len(reviewIDs) > 0 {
+		if err := tx.Select("id", "phone_id").Where("id IN ?", reviewIDs).Find(&repliedTo).Error; err != nil {
+			return err
+		}
+	}
+	reviewPhones := make(map[uint]uint, len(repliedTo))
+	phoneIDs := make([]uint, 0, len(reviews)+len(repliedTo))
+	for _, review := range repliedTo {
+		reviewPhones[review.ID] = review.PhoneID
+		phoneIDs = append(phoneIDs, review.PhoneID)
+	}
+	for _, review := range reviews {
+		phoneIDs = append(phoneIDs, review.PhoneID)
+	}
+
+	phones := map[uint]string{}
+	if len(phoneIDs) > 0 {
+		var found []models.Phone
+		if err := tx.Unscoped().Select("id", "name", "brand").Where("id IN ?", phoneIDs).Find(&found).Error; err != nil {
+			return err
+		}
+		for _, phone := range found {
+			phones[phone.ID] = phone.Brand + " " + phone.Name
+		}
+	}
+
+	authors := map[uint]string{}
+	if len(authorIDs) > 0 {
+		var found []models.User
+		if err := tx.Select("id", "username").Where("id IN ?", authorIDs).Find(&found).Error; err != nil {
+			return err
+		}
+		for _, author := range found {
+			authors[author.ID] = author.Username
+		}
+	}
+
+	for _, review := range reviews {
+		email.Reviews = append(email.Reviews, digestReview{
+			Phone:   phones[review.PhoneID],
+			Author:  authors[review.UserID],
+			Rating:  review.Rating,
+			Excerpt: excerpt(review.Content),
+		})
+	}
+	for _, reply := range replies {
+		email.Replies = append(email.Replies, digestReply{
+			Phone:     phones[reviewPhones[reply.ReviewID]],
+			Author:    authors[reply.UserID],
+			OnComment: reply.ParentID != nil,
+			Excerpt:   excerpt(reply.Content),
+		})
+	}
+	return nil
+}
+
+// excerpt shortens text to digestExcerptLength characters.
+func excerpt(text string) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= digestExcerptLength {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:digestExcerptLength])) + "…"
+}
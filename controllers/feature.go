@@ -5,8 +5,9 @@ import (
 	"backend-vercel-phone-review/models"
 	"backend-vercel-phone-review/utils"
 	"net/http"
-
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -14,7 +15,7 @@ import (
 
 // CreateFeature godoc
 // @Summary Create a new feature
-// @Description Create a new feature
+// @Description Create a new feature. When its name matches a spec of the registry, by key or label, its details are parsed as that spec's value, such as "5,000 mAh" for the battery capacity; parse_error tells why they could not be.
 // @Tags features
 // @Accept json
 // @Produce json
@@ -23,20 +24,57 @@ import (
 // @Param phone_id path int true "Phone ID"
 // @Param feature body models.Feature true "Feature"
 // @Success 200 {object} models.Feature
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Router /phones/{phone_id}/features [post]
 func CreateFeature(c *gin.Context) {
-	phoneID := c.Param("phone_id")
-	var feature models.Feature
+	userID, _ := currentUserID(c)
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
 
+	var feature models.Feature
 	if err := c.ShouldBindJSON(&feature); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	// Set PhoneID for the feature
-	feature.PhoneID = utils.StringToUint(phoneID)
+	feature.PhoneID = phone.ID
 
-	if err := config.DB.Create(&feature).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		definitions, err := loadSpecDefinitions(tx)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&feature).Error; err != nil {
+			return err
+		}
+		if err := applyFeatureSpec(tx, &feature, definitions, true); err != nil {
+			return err
+		}
+		if err := saveFeatureSpec(tx, &feature); err != nil {
+			return err
+		}
+		return queuePhoneFollowers(tx, phoneFollowersJob{
+			Update:  phoneUpdateFeature,
+			PhoneID: feature.PhoneID,
+			ActorID: userID,
+			Detail:  feature.Name,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -46,7 +84,7 @@ func CreateFeature(c *gin.Context) {
 
 // UpdateFeature godoc
 // @Summary Update a feature of a phone
-// @Description Update a feature of a phone
+// @Description Update a feature of a phone, parsing its details as a spec value like CreateFeature does
 // @Tags features
 // @Accept  json
 // @Produce  json
@@ -72,7 +110,7 @@ func UpdateFeature(c *gin.Context) {
 
 	var feature models.Feature
 	if err := c.ShouldBindJSON(&feature); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
 		return
 	}
 
@@ -96,17 +134,27 @@ func UpdateFeature(c *gin.Context) {
 	existingFeature.Name = feature.Name
 	existingFeature.Details = feature.Details
 
-	if err := config.DB.Save(&existingFeature).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		definitions, err := loadSpecDefinitions(tx)
+		if err != nil {
+			return err
+		}
+		if err := applyFeatureSpec(tx, &existingFeature, definitions, true); err != nil {
+			return err
+		}
+		return tx.Save(&existingFeature).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "feature updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "feature updated successfully", "feature": existingFeature})
 }
 
 // DeleteFeature godoc
 // @Summary Delete a feature of a phone
-// @Description Delete a feature of a phone
+// @Description Delete a feature of a phone, along with the spec value parsed from it
 // @Tags features
 // @Accept  json
 // @Produce  json
@@ -146,10 +194,137 @@ func DeleteFeature(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Delete(&existingFeature).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := clearFeatureSpec(tx, existingFeature.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&existingFeature).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "feature deleted successfully"})
 }
+
+// ReparseFeatures godoc
+// @Summary Parse every feature again
+// @Description Match every feature to the spec registry and parse its details again, for example after adding a spec, and report the ones that could not be parsed. Values set on a phone directly through PUT /phones/{phone_id}/specs are kept. Admins only.
+// @Tags specs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SpecParseReport
+// @Router /specs/reparse [post]
+func ReparseFeatures(c *gin.Context) {
+	report := models.SpecParseReport{Failed: []models.FeatureParseFailure{}}
+
+	definitions, err := loadSpecDefinitions(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var features []models.Feature
+	result := config.DB.FindInBatches(&features, 200, func(tx *gorm.DB, batch int) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			for i := range features {
+				feature := &features[i]
+				if err := applyFeatureSpec(tx, feature, definitions, false); err != nil {
+					return err
+				}
+				if err := saveFeatureSpec(tx, feature); err != nil {
+					return err
+				}
+
+				report.Features++
+				if feature.SpecKey == "" {
+					continue
+				}
+				report.Matched++
+				if feature.ParseError == "" {
+					report.Parsed++
+					continue
+				}
+				report.Failed = append(report.Failed, models.FeatureParseFailure{
+					FeatureID: feature.ID,
+					PhoneID:   feature.PhoneID,
+					Name:      feature.Name,
+					Details:   feature.Details,
+					SpecKey:   feature.SpecKey,
+					Error:     feature.ParseError,
+				})
+			}
+			return nil
+		})
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// applyFeatureSpec matches a saved feature to a spec of the registry by key
+// or label and parses its details as a value of that spec. A value that
+// parses becomes the phone's value for the spec; one that does not is
+// recorded in the feature's ParseError. The value the feature set before
+// is removed first, so a feature renamed away from a spec leaves nothing
+// behind. A value set on the phone directly is only replaced when replace
+// is true.
+func applyFeatureSpec(tx *gorm.DB, feature *models.Feature, definitions []models.SpecDefinition, replace bool) error {
+	feature.SpecKey, feature.Value, feature.Unit, feature.ParseError = "", nil, "", ""
+	if err := clearFeatureSpec(tx, feature.ID); err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(feature.Name)
+	key := strings.ReplaceAll(utils.Slugify(name), "-", "_")
+	i := slices.IndexFunc(definitions, func(definition models.SpecDefinition) bool {
+		return definition.Key == key || strings.EqualFold(definition.Label, name)
+	})
+	if i < 0 {
+		return nil
+	}
+	definition := definitions[i]
+	feature.SpecKey = definition.Key
+
+	value, message := parseSpecText(definition, feature.Details)
+	if message != "" {
+		feature.ParseError = message
+		return nil
+	}
+	if value.Number != nil {
+		feature.Value = value.Number
+		feature.Unit = definition.Unit
+	}
+
+	if !replace {
+		var direct int64
+		if err := tx.Model(&models.SpecValue{}).
+			Where("phone_id = ? AND spec_definition_id = ? AND feature_id IS NULL", feature.PhoneID, definition.ID).
+			Count(&direct).Error; err != nil {
+			return err
+		}
+		if direct > 0 {
+			return nil
+		}
+	}
+
+	value.PhoneID = feature.PhoneID
+	value.FeatureID = &feature.ID
+	return saveSpecValues(tx, []models.SpecValue{value})
+}
+
+// saveFeatureSpec stores what applyFeatureSpec found out on the feature.
+func saveFeatureSpec(tx *gorm.DB, feature *models.Feature) error {
+	return tx.Model(feature).Select("spec_key", "value", "unit", "parse_error").Updates(feature).Error
+}
+
+// clearFeatureSpec removes the spec value parsed from a feature.
+func clearFeatureSpec(tx *gorm.DB, featureID uint) error {
+	return tx.Unscoped().Where("feature_id = ?", featureID).Delete(&models.SpecValue{}).Error
+}
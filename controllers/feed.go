@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Feed items are ordered newest first. Reviews and comments created in the
+// same instant are told apart by their kind, stored in the cursor's Score,
+// and then by ID.
+const (
+	feedKindReview  = 0
+	feedKindComment = 1
+)
+
+// GetFeed godoc
+// @Summary Get my activity feed
+// @Description Get the published reviews and comments of the reviewers the authenticated user follows, newest first. The feed is assembled when it is read, so following someone shows their past activity too.
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} models.FeedPage
+// @Failure 400 {object} map[string]string
+// @Router /feed [get]
+func GetFeed(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	p, ok := parsePage(c, sortNewest)
+	if !ok {
+		return
+	}
+
+	followed := config.DB.Model(&models.Follow{}).
+		Select("target_id").
+		Where("user_id = ? AND target_type = ?", userID, models.FollowTargetUser)
+
+	var reviews []models.Review
+	reviewQuery := config.DB.Scopes(publishedReviews).Where("reviews.user_id IN (?)", followed)
+	if err := feedKeyset(reviewQuery, "reviews", feedKindReview, p).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var comments []models.Comment
+	commentQuery := config.DB.Scopes(publishedComments).
+		Joins("JOIN reviews ON reviews.id = comments.review_id AND reviews.deleted_at IS NULL AND reviews.status = ?", models.ReviewStatusPublished).
+		Where("comments.user_id IN (?)", followed)
+	if err := feedKeyset(commentQuery, "comments", feedKindComment, p).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := renderMentions(comments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Merge the two newest-first lists, keeping one item more than the page
+	// so we know whether another page follows.
+	items := make([]models.FeedItem, 0, p.Limit+1)
+	r, k := 0, 0
+	for len(items) <= p.Limit && (r < len(reviews) || k < len(comments)) {
+		takeReview := k >= len(comments) ||
+			(r < len(reviews) && !reviews[r].CreatedAt.Before(comments[k].CreatedAt))
+		if takeReview {
+			review := reviews[r]
+			items = append(items, models.FeedItem{Type: models.FeedItemReview, CreatedAt: review.CreatedAt, ActorID: review.UserID, Review: &review})
+			r++
+			continue
+		}
+		comment := comments[k]
+		items = append(items, models.FeedItem{Type: models.FeedItemComment, CreatedAt: comment.CreatedAt, ActorID: comment.UserID, Comment: &comment})
+		k++
+	}
+
+	response := models.FeedPage{Items: items}
+	if len(items) > p.Limit {
+		response.Items = items[:p.Limit]
+		last := response.Items[p.Limit-1]
+		cursor := utils.Cursor{CreatedAt: last.CreatedAt}
+		if last.Review != nil {
+			cursor.ID, cursor.Score = last.Review.ID, feedKindReview
+		} else {
+			cursor.ID, cursor.Score = last.Comment.ID, feedKindComment
+		}
+		response.NextCursor = utils.EncodeCursor(cursor)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// feedKeyset orders one of the feed's sources newest first and continues
+// after the cursor, which may point at an item of either kind.
+func feedKeyset(query *gorm.DB, table string, kind int, p page) *gorm.DB {
+	if p.Cursor != nil {
+		switch {
+		case kind > p.Cursor.Score:
+			query = query.Where(table+".created_at <= ?", p.Cursor.CreatedAt)
+		case kind == p.Cursor.Score:
+			query = query.Where("("+table+".created_at < ? OR ("+table+".created_at = ? AND "+table+".id < ?))",
+				p.Cursor.CreatedAt, p.Cursor.CreatedAt, p.Cursor.ID)
+		default:
+			query = query.Where(table+".created_at < ?", p.Cursor.CreatedAt)
+		}
+	}
+
+	return query.Order(table + ".created_at DESC").Order(table + ".id DESC").Limit(p.Limit + 1)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/filters"
+	"backend-vercel-phone-review/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recentPostsLimit is how many of an author's latest posts the repetition
+// check compares new text against.
+const recentPostsLimit = 20
+
+// filterReview runs the content filter chain over review text, comparing it
+// with the author's other reviews. reviewID is 0 for a new review.
+func filterReview(userID, reviewID uint, text string) (filters.Result, error) {
+	var recent []string
+	if err := config.DB.Model(&models.Review{}).
+		Where("user_id = ? AND id <> ?", userID, reviewID).
+		Order("created_at DESC").Limit(recentPostsLimit).
+		Pluck("content", &recent).Error; err != nil {
+		return filters.Result{}, err
+	}
+
+	return filters.Default().Run(filters.Input{Text: text, Recent: recent}), nil
+}
+
+// filterComment runs the content filter chain over comment text, comparing
+// it with the author's other comments. commentID is 0 for a new comment.
+func filterComment(userID, commentID uint, text string) (filters.Result, error) {
+	var recent []string
+	if err := config.DB.Model(&models.Comment{}).
+		Where("user_id = ? AND id <> ?", userID, commentID).
+		Order("created_at DESC").Limit(recentPostsLimit).
+		Pluck("content", &recent).Error; err != nil {
+		return filters.Result{}, err
+	}
+
+	return filters.Default().Run(filters.Input{Text: text, Recent: recent}), nil
+}
+
+// filterPoints runs the content filter chain over each pro or con of a
+// review and returns the possibly masked points with their combined
+// result. Points are short and often alike across reviews, so they are not
+// checked for repetition against the author's other posts.
+func filterPoints(points []string) ([]string, filters.Result) {
+	if points == nil {
+		return nil, filters.Combine()
+	}
+
+	filtered := make([]string, len(points))
+	results := make([]filters.Result, len(points))
+	for i, point := range points {
+		results[i] = filters.Default().Run(filters.Input{Text: point})
+		filtered[i] = results[i].Text
+	}
+	return filtered, filters.Combine(results...)
+}
+
+// screenReview runs the content filter over the text, pros and cons of a
+// new or edited review and settles its moderation status. It writes the
+// error response itself and returns false when the review must not be
+// saved.
+func screenReview(c *gin.Context, review *models.Review, previous *models.Review) bool {
+	content, err := filterReview(review.UserID, review.ID, review.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	pros, prosResult := filterPoints(review.Pros)
+	cons, consResult := filterPoints(review.Cons)
+
+	result := filters.Combine(content, prosResult, consResult)
+	if result.Verdict == filters.VerdictReject {
+		respondFilterRejected(c, result)
+		return false
+	}
+
+	review.Content = content.Text
+	review.Pros = pros
+	review.Cons = cons
+	review.FilterVerdict = result.Verdict
+	review.FilterReasons = result.Reasons
+
+	if err := applyReviewStatus(review, previous, result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// screenComment runs the content filter over a new or edited comment and
+// holds it for moderation when the filter asks to. It writes the error
+// response itself and returns false when the comment must not be saved.
+func screenComment(c *gin.Context, comment *models.Comment) bool {
+	result, err := filterComment(comment.UserID, comment.ID, comment.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if result.Verdict == filters.VerdictReject {
+		respondFilterRejected(c, result)
+		return false
+	}
+
+	comment.Content = result.Text
+	comment.FilterVerdict = result.Verdict
+	comment.FilterReasons = result.Reasons
+
+	switch {
+	case comment.Status == models.CommentStatusHidden:
+	case result.Verdict == filters.VerdictHold:
+		comment.Status = models.CommentStatusPending
+	default:
+		comment.Status = models.CommentStatusPublished
+	}
+	return true
+}
+
+func respondFilterRejected(c *gin.Context, result filters.Result) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "content rejected", "reasons": result.Reasons})
+}
+
+func filterHoldReason(result filters.Result) string {
+	return "held by content filter: " + strings.Join(result.Reasons, ", ")
+}
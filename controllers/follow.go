@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/outbox"
+	"backend-vercel-phone-review/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	phoneUpdateReview  = "review"
+	phoneUpdateFeature = "feature"
+	phoneUpdatePrice   = "price"
+)
+
+// phoneFollowersJob is the outbox payload of an update sent to everyone
+// following a phone or its brand.
+type phoneFollowersJob struct {
+	Update   string `json:"update"`
+	PhoneID  uint   `json:"phone_id"`
+	ActorID  uint   `json:"actor_id"`
+	ReviewID *uint  `json:"review_id,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// FollowPhone godoc
+// @Summary Follow a phone
+// @Description Get notified about new reviews, features and price changes of a phone. Following twice is harmless.
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param phone_id path int true "Phone ID"
+// @Success 200 {object} models.Follow
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /phones/{phone_id}/follow [put]
+func FollowPhone(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	saveFollow(c, models.Follow{UserID: userID, TargetType: models.FollowTargetPhone, TargetID: phone.ID})
+}
+
+// UnfollowPhone godoc
+// @Summary Unfollow a phone
+// @Description Stop getting notified about a phone
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param phone_id path int true "Phone ID"
+// @Success 200 {object} map[string]string
+// @Router /phones/{phone_id}/follow [delete]
+func UnfollowPhone(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	deleteFollow(c, models.Follow{UserID: userID, TargetType: models.FollowTargetPhone, TargetID: utils.StringToUint(c.Param("phone_id"))})
+}
+
+// FollowBrand godoc
+// @Summary Follow a brand
+// @Description Get notified about new reviews, features and price changes of every phone of a brand. Following twice is harmless.
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param slug path string true "Brand slug, e.g. samsung"
+// @Success 200 {object} models.Follow
+// @Failure 404 {object} map[string]string
+// @Router /brands/{slug}/follow [put]
+func FollowBrand(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	brands, err := brandNames()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	slug := c.Param("slug")
+	if _, ok := brands[slug]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "brand not found"})
+		return
+	}
+
+	saveFollow(c, models.Follow{UserID: userID, TargetType: models.FollowTargetBrand, Brand: slug})
+}
+
+// UnfollowBrand godoc
+// @Summary Unfollow a brand
+// @Description Stop getting notified about a brand
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param slug path string true "Brand slug"
+// @Success 200 {object} map[string]string
+// @Router /brands/{slug}/follow [delete]
+func UnfollowBrand(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	deleteFollow(c, models.Follow{UserID: userID, TargetType: models.FollowTargetBrand, Brand: c.Param("slug")})
+}
+
+// FollowUser godoc
+// @Summary Follow a reviewer
+// @Description Add a reviewer's new reviews and comments to the authenticated user's feed. Following twice is harmless.
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.Follow
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/follow [put]
+func FollowUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	followedID, ok := pathID(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Select("id").First(&user, followedID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if user.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot follow yourself"})
+		return
+	}
+
+	saveFollow(c, models.Follow{UserID: userID, TargetType: models.FollowTargetUser, TargetID: user.ID})
+}
+
+// UnfollowUser godoc
+// @Summary Unfollow a reviewer
+// @Description Stop seeing a reviewer's activity in the authenticated user's feed
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Router /users/{id}/follow [delete]
+func UnfollowUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	deleteFollow(c, models.Follow{UserID: userID, TargetType: models.FollowTargetUser, TargetID: utils.StringToUint(c.Param("id"))})
+}
+
+// GetMyFollows godoc
+// @Summary Get what I follow
+// @Description Get the phones, brands and reviewers the authenticated user follows
+// @Tags follows
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.FollowList
+// @Router /follows [get]
+func GetMyFollows(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var follows []models.Follow
+	if err := config.DB.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&follows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var phoneIDs, userIDs []uint
+	response := models.FollowList{Phones: []models.Phone{}, Brands: []models.BrandFollow{}, Users: []models.FollowedUser{}}
+	brands, err := brandNames()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, follow := range follows {
+		switch follow.TargetType {
+		case models.FollowTargetPhone:
+			phoneIDs = append(phoneIDs, follow.TargetID)
+		case models.FollowTargetUser:
+			userIDs = append(userIDs, follow.TargetID)
+		default:
+			response.Brands = append(response.Brands, models.BrandFollow{Slug: follow.Brand, Name: brands[follow.Brand]})
+		}
+	}
+
+	if len(phoneIDs) > 0 {
+		if err := config.DB.Where("id IN ?", phoneIDs).Order("brand ASC, name ASC").Find(&response.Phones).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if len(userIDs) > 0 {
+		if err := config.DB.Model(&models.User{}).Where("id IN ?", userIDs).Order("username ASC").Find(&response.Users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func saveFollow(c *gin.Context, follow models.Follow) {
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Where("user_id = ? AND target_type = ? AND target_id = ? AND brand = ?", follow.UserID, follow.TargetType, follow.TargetID, follow.Brand).
+		First(&follow).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, follow)
+}
+
+// deleteFollow removes a follow for good, so following again later creates
+// a fresh row instead of clashing with a soft deleted one.
+func deleteFollow(c *gin.Context, follow models.Follow) {
+	if err := config.DB.Unscoped().
+		Where("user_id = ? AND target_type = ? AND target_id = ? AND brand = ?", follow.UserID, follow.TargetType, follow.TargetID, follow.Brand).
+		Delete(&models.Follow{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "unfollowed successfully"})
+}
+
+// brandNames maps the slug of every brand to its name.
+func brandNames() (map[string]string, error) {
+	var list []models.Brand
+	if err := config.DB.Select("slug", "name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	brands := make(map[string]string, len(list))
+	for _, brand := range list {
+		brands[brand.Slug] = brand.Name
+	}
+	return brands, nil
+}
+
+// queueReviewFollowers queues a new review notification for the followers
+// of the phone when a review has just become visible.
+func queueReviewFollowers(tx *gorm.DB, review models.Review, previous *models.Review) error {
+	if !becamePublished(review, previous) {
+		return nil
+	}
+
+	reviewID := review.ID
+	return queuePhoneFollowers(tx, phoneFollowersJob{
+		Update:   phoneUpdateReview,
+		PhoneID:  review.PhoneID,
+		ActorID:  review.UserID,
+		ReviewID: &reviewID,
+	})
+}
+
+// queuePhoneFollowers queues an update for everyone following a phone or
+// its brand through tx, the transaction of the change.
+func queuePhoneFollowers(tx *gorm.DB, job phoneFollowersJob) error {
+	return outbox.Enqueue(tx, jobPhoneFollowers, job, outbox.Options{})
+}
+
+// notifyPhoneFollowers notifies every follower of a phone or its brand
+// about an update, once even when they follow both.
+func notifyPhoneFollowers(ctx context.Context, tx *gorm.DB, raw json.RawMessage) error {
+	var job phoneFollowersJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	var phone models.Phone
+	if err := tx.First(&phone, job.PhoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	// The slug outlives renames of the brand, so it is not derived from the name
+	var brand models.Brand
+	if err := tx.Select("slug").First(&brand, phone.BrandID).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	var followers []uint
+	if err := tx.Model(&models.Follow{}).
+		Where("(target_type = ? AND target_id = ?) OR (target_type = ? AND brand = ?)",
+			models.FollowTargetPhone, phone.ID, models.FollowTargetBrand, brand.Slug).
+		Distinct("user_id").
+		Pluck("user_id", &followers).Error; err != nil {
+		return err
+	}
+
+	name := phone.Brand + " " + phone.Name
+	message := fmt.Sprintf("%s has a new review", name)
+	switch job.Update {
+	case phoneUpdateFeature:
+		message = fmt.Sprintf("%s has a new feature: %s", name, job.Detail)
+	case phoneUpdatePrice:
+		message = fmt.Sprintf("The price of %s changed %s", name, job.Detail)
+	}
+
+	phoneID := phone.ID
+	for _, userID := range followers {
+		if err := notify(tx, models.Notification{
+			UserID:   userID,
+			ActorID:  job.ActorID,
+			Type:     models.NotificationFollow,
+			PhoneID:  &phoneID,
+			ReviewID: job.ReviewID,
+			Message:  message,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// priceChange describes a phone's price change for a notification, or
+// returns "" when the price stayed the same.
+func priceChange(before, after *float64) string {
+	switch {
+	case before == nil && after == nil:
+		return ""
+	case before == nil:
+		return fmt.Sprintf("to %.2f", *after)
+	case after == nil:
+		return ""
+	case *before == *after:
+		return ""
+	default:
+		return fmt.Sprintf("from %.2f to %.2f", *before, *after)
+	}
+}
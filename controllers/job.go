@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/outbox"
+	"backend-vercel-phone-review/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	jobWebhookFanout  = "webhooks.fanout"
+	jobWebhookDeliver = "webhooks.deliver"
+	jobPhoneFollowers = "follows.phone_update"
+	jobDigestSend     = "digests.send"
+	jobDigestConfirm  = "digests.confirm"
+	jobEventPublish   = "events.publish"
+
+	// jobRunBudget bounds how long one call of the cron endpoint keeps
+	// running passes, to stay inside the serverless function timeout.
+	jobRunBudget = 8 * time.Second
+)
+
+// NewWorker builds the outbox worker with every job handler and the
+// settings from the environment.
+func NewWorker() *outbox.Worker {
+	policy := config.OutboxWorker()
+	return &outbox.Worker{
+		DB: config.DB,
+		Handlers: map[string]outbox.Handler{
+			jobWebhookFanout:  fanOutWebhook,
+			jobWebhookDeliver: deliverWebhook,
+			jobPhoneFollowers: notifyPhoneFollowers,
+			jobDigestSend:     sendDigest,
+			jobDigestConfirm:  sendDigestConfirmation,
+			jobEventPublish:   publishQueuedEvent,
+		},
+		OutsideTransaction: map[string]bool{
+			jobWebhookDeliver: true,
+			jobDigestSend:     true,
+			jobDigestConfirm:  true,
+		},
+		BatchSize:   policy.BatchSize,
+		MaxAttempts: policy.MaxAttempts,
+		BaseDelay:   policy.BaseDelay,
+		MaxDelay:    policy.MaxDelay,
+		Lease:       policy.Lease,
+	}
+}
+
+// RunJobs godoc
+// @Summary Run due background jobs
+// @Description Queue the email digests that are due, then run the outbox jobs that are due, pass after pass, for a few seconds. Meant for the cron scheduler, which authenticates with the CRON_SECRET bearer token.
+// @Tags jobs
+// @Produce json
+// @Param Authorization header string true "Bearer CRON_SECRET"
+// @Success 200 {object} models.JobRunSummary
+// @Failure 401 {object} map[string]string
+// @Router /jobs/run [get]
+func RunJobs(c *gin.Context) {
+	worker := NewWorker()
+	deadline := time.Now().Add(jobRunBudget)
+
+	if _, err := QueueDueDigests(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total models.JobRunSummary
+	for time.Now().Before(deadline) {
+		summary, err := worker.RunOnce(c.Request.Context())
+		total.Claimed += summary.Claimed
+		total.Done += summary.Done
+		total.Retried += summary.Retried
+		total.Dead += summary.Dead
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "summary": total})
+			return
+		}
+		if summary.Claimed == 0 {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, total)
+}
+
+// GetJobs godoc
+// @Summary Get background jobs
+// @Description Get outbox jobs, newest first, to inspect the queue and the dead letters. Admins only.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param status query string false "Only jobs in this status: pending, running, done or dead"
+// @Param kind query string false "Only jobs of this kind"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} models.OutboxJobPage
+// @Failure 403 {object} map[string]string
+// @Router /jobs [get]
+func GetJobs(c *gin.Context) {
+	p, ok := parsePage(c, sortNewest)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Model(&models.OutboxJob{})
+	switch status := c.Query("status"); status {
+	case "":
+	case models.OutboxJobPending, models.OutboxJobRunning, models.OutboxJobDone, models.OutboxJobDead:
+		query = query.Where("outbox_jobs.status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job status"})
+		return
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("outbox_jobs.kind = ?", kind)
+	}
+
+	var jobs []models.OutboxJob
+	if err := keyset(query, "outbox_jobs", p).Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.OutboxJobPage{Items: jobs}
+	if len(jobs) > p.Limit {
+		response.Items = jobs[:p.Limit]
+		last := response.Items[p.Limit-1]
+		response.NextCursor = utils.EncodeCursor(utils.Cursor{ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+	if response.Items == nil {
+		response.Items = []models.OutboxJob{}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RetryJob godoc
+// @Summary Retry a dead background job
+// @Description Put a dead-lettered job back in the queue with its attempts reset. Admins only.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.OutboxJob
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /jobs/{id}/retry [post]
+func RetryJob(c *gin.Context) {
+	jobID, ok := pathID(c, "id", "job")
+	if !ok {
+		return
+	}
+
+	var job models.OutboxJob
+	if err := config.DB.First(&job, jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if job.Status != models.OutboxJobDead {
+		c.JSON(http.StatusConflict, gin.H{"error": "only dead jobs can be retried"})
+		return
+	}
+
+	job.Status = models.OutboxJobPending
+	job.Attempts = 0
+	job.RunAt = time.Now()
+	if err := config.DB.Model(&job).Select("status", "attempts", "run_at").Updates(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
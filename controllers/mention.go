@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var mentionPattern = regexp.MustCompile(`(^|[^\w@])@([A-Za-z0-9_.-]*[A-Za-z0-9_])`)
+
+// parseMentions returns the distinct usernames mentioned in text, in lower
+// case and in the order they first appear. Usernames are matched without
+// regard to case, the way MySQL compares them anyway.
+func parseMentions(text string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := strings.ToLower(match[2])
+		if !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+// saveMentions brings the mention records of a comment in line with the
+// usernames its content mentions and returns the users that were not
+// mentioned before. Unknown usernames and the author are ignored.
+func saveMentions(tx *gorm.DB, comment *models.Comment) ([]models.User, error) {
+	var users []models.User
+	if usernames := parseMentions(comment.Content); len(usernames) > 0 {
+		if err := tx.Select("id", "username").Where("LOWER(username) IN ? AND id <> ?", usernames, comment.UserID).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var existing []models.Mention
+	if err := tx.Where("comment_id = ?", comment.ID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	known := make(map[uint]bool, len(existing))
+	for _, mention := range existing {
+		known[mention.UserID] = true
+	}
+
+	mentioned := make([]uint, 0, len(users))
+	var added []models.User
+	mentions := make([]models.Mention, 0, len(users))
+	for _, user := range users {
+		mentioned = append(mentioned, user.ID)
+		mentions = append(mentions, models.Mention{CommentID: comment.ID, UserID: user.ID, Username: user.Username})
+		if !known[user.ID] {
+			added = append(added, user)
+		}
+	}
+
+	stale := tx.Unscoped().Where("comment_id = ?", comment.ID)
+	if len(mentioned) > 0 {
+		stale = stale.Where("user_id NOT IN ?", mentioned)
+	}
+	if err := stale.Delete(&models.Mention{}).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range mentions {
+		if known[mentions[i].UserID] {
+			continue
+		}
+		if err := tx.Create(&mentions[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	comment.Mentions = mentions
+	return added, nil
+}
+
+// notifyMentions tells each newly mentioned user about a published comment.
+func notifyMentions(tx *gorm.DB, comment models.Comment, users []models.User) error {
+	if comment.Status != models.CommentStatusPublished {
+		return nil
+	}
+
+	for _, user := range users {
+		reviewID, commentID := comment.ReviewID, comment.ID
+		if err := notify(tx, models.Notification{
+			UserID:    user.ID,
+			ActorID:   comment.UserID,
+			Type:      models.NotificationMention,
+			ReviewID:  &reviewID,
+			CommentID: &commentID,
+			Message:   "You were mentioned in a comment",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderMentions loads the mentions of the given comments and renders their
+// content as HTML with every resolved mention linked to the user's page.
+func renderMentions(comments []models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+
+	var mentions []models.Mention
+	if err := config.DB.Where("comment_id IN ?", ids).Find(&mentions).Error; err != nil {
+		return err
+	}
+
+	byComment := make(map[uint][]models.Mention)
+	for _, mention := range mentions {
+		byComment[mention.CommentID] = append(byComment[mention.CommentID], mention)
+	}
+
+	for i := range comments {
+		comments[i].Mentions = byComment[comments[i].ID]
+		if comments[i].Mentions == nil {
+			comments[i].Mentions = []models.Mention{}
+		}
+		comments[i].Rendered = renderContent(comments[i].Content, comments[i].Mentions)
+	}
+	return nil
+}
+
+func renderContent(content string, mentions []models.Mention) string {
+	users := make(map[string]uint, len(mentions))
+	for _, mention := range mentions {
+		users[strings.ToLower(mention.Username)] = mention.UserID
+	}
+
+	escaped := html.EscapeString(content)
+	if len(users) == 0 {
+		return escaped
+	}
+
+	return mentionPattern.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := mentionPattern.FindStringSubmatch(match)
+		userID, ok := users[strings.ToLower(parts[2])]
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf(`%s<a href="/api/v1/users/%d" class="mention">@%s</a>`, parts[1], userID, parts[2])
+	})
+}
+
+// renderComment is renderMentions for a single comment.
+func renderComment(comment *models.Comment) error {
+	comments := []models.Comment{*comment}
+	if err := renderMentions(comments); err != nil {
+		return err
+	}
+	*comment = comments[0]
+	return nil
+}
@@ -0,0 +1,437 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/filters"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// publishedReviews limits a review query to reviews visible to the public.
+func publishedReviews(db *gorm.DB) *gorm.DB {
+	return db.Where("reviews.status = ?", models.ReviewStatusPublished)
+}
+
+// reviewStatusFor decides whether a new or edited review by the user goes
+// live straight away or waits in the moderation queue. reviewID is the
+// review being edited, or 0 for a new one.
+func reviewStatusFor(userID, reviewID uint) (string, error) {
+	rules := config.ReviewModerationRules()
+	if rules.AutoPublish == config.AutoPublishAll {
+		return models.ReviewStatusPublished, nil
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	if user.Role == models.RoleModerator || user.Role == models.RoleAdmin {
+		return models.ReviewStatusPublished, nil
+	}
+	if rules.AutoPublish == config.AutoPublishNone {
+		return models.ReviewStatusPending, nil
+	}
+	if user.Trusted {
+		return models.ReviewStatusPublished, nil
+	}
+
+	var published int64
+	if err := config.DB.Model(&models.Review{}).Scopes(publishedReviews).
+		Where("user_id = ? AND id <> ?", userID, reviewID).
+		Count(&published).Error; err != nil {
+		return "", err
+	}
+	if published >= rules.TrustedAfter {
+		return models.ReviewStatusPublished, nil
+	}
+
+	return models.ReviewStatusPending, nil
+}
+
+// GetModerationQueue godoc
+// @Summary Get the review moderation queue
+// @Description Get reviews in a moderation status, oldest first. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param status query string false "Review status: pending (default), rejected, hidden or published"
+// @Success 200 {object} []models.Review
+// @Failure 403 {object} map[string]string
+// @Router /moderation/reviews [get]
+func GetModerationQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewStatusPending)
+	switch status {
+	case models.ReviewStatusPending, models.ReviewStatusPublished, models.ReviewStatusRejected, models.ReviewStatusHidden:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review status"})
+		return
+	}
+
+	var reviews []models.Review
+	if err := config.DB.Where("status = ?", status).Order("created_at ASC, id ASC").Preload("SubRatings").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// ApproveReview godoc
+// @Summary Approve a review
+// @Description Publish a review from the moderation queue. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /moderation/reviews/{id}/approve [post]
+func ApproveReview(c *gin.Context) {
+	moderateReview(c, models.ReviewStatusPublished, "")
+}
+
+// RejectReview godoc
+// @Summary Reject a review
+// @Description Reject a review with a reason shown to its author. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Review ID"
+// @Param moderation body models.ModerationRequest true "Reason"
+// @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /moderation/reviews/{id}/reject [post]
+func RejectReview(c *gin.Context) {
+	var input models.ModerationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	moderateReview(c, models.ReviewStatusRejected, input.Reason)
+}
+
+// HideReview godoc
+// @Summary Hide a review
+// @Description Take a published review down with a reason. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Review ID"
+// @Param moderation body models.ModerationRequest true "Reason"
+// @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /moderation/reviews/{id}/hide [post]
+func HideReview(c *gin.Context) {
+	var input models.ModerationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	moderateReview(c, models.ReviewStatusHidden, input.Reason)
+}
+
+// VerifyReview godoc
+// @Summary Mark a review as verified
+// @Description Mark a review as written by a verified owner of the phone, or clear the mark. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Review ID"
+// @Param verify body models.VerifyRequest true "Verified"
+// @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /moderation/reviews/{id}/verify [post]
+func VerifyReview(c *gin.Context) {
+	var input models.VerifyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	reviewID, ok := pathID(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.First(&review, reviewID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if err := config.DB.Model(&review).Update("verified", *input.Verified).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+func moderateReview(c *gin.Context, status, reason string) {
+	moderatorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	reviewID, ok := pathID(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.First(&review, reviewID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	previous := review
+	now := time.Now()
+	review.Status = status
+	review.ModerationReason = reason
+	review.ModeratedBy = &moderatorID
+	review.ModeratedAt = &now
+	updated := false
+	err := withEvents(func(tx *gorm.DB) error {
+		var err error
+		updated, err = updateReviewStatus(tx, review, previous)
+		if err != nil || !updated {
+			return err
+		}
+		return notifyReviewModerated(tx, review, moderatorID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("review is already %s or was moderated in the meantime", status)})
+		return
+	}
+
+	announceReview(review, &previous)
+	c.JSON(http.StatusOK, review)
+}
+
+// updateReviewStatus saves the moderation fields of review, unless it
+// already has the new status or its status has moved on from the one in
+// previous in the meantime, and queues the webhooks and follower updates
+// the change calls for. It reports whether the review was updated. Once tx
+// commits, the caller announces the review with announceReview.
+func updateReviewStatus(tx *gorm.DB, review models.Review, previous models.Review) (bool, error) {
+	if review.Status == previous.Status {
+		return false, nil
+	}
+
+	result := tx.Model(&review).
+		Where("status = ?", previous.Status).
+		Select("status", "moderation_reason", "moderated_by", "moderated_at").
+		Updates(&review)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	if err := queueReviewWebhooks(tx, review, &previous); err != nil {
+		return false, err
+	}
+	if err := queueReviewFollowers(tx, review, &previous); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// becamePublished reports whether a save made a review visible. previous is
+// nil for new reviews.
+func becamePublished(review models.Review, previous *models.Review) bool {
+	return review.Status == models.ReviewStatusPublished &&
+		(previous == nil || previous.Status != models.ReviewStatusPublished)
+}
+
+// applyReviewStatus runs the auto-publish rules on a new review, or on an
+// edit that changed it. previous is nil for new reviews. Reviews that were
+// hidden or rejected keep that status when their author edits them, so an
+// edit cannot undo a moderation decision, and reviews the content filter
+// held wait in the queue whoever wrote them.
+func applyReviewStatus(review *models.Review, previous *models.Review, filtered filters.Result) error {
+	if previous != nil && (!reviewChanged(*previous, *review) ||
+		review.Status == models.ReviewStatusHidden ||
+		review.Status == models.ReviewStatusRejected) {
+		return nil
+	}
+
+	status, err := reviewStatusFor(review.UserID, review.ID)
+	if err != nil {
+		return err
+	}
+
+	review.Status = status
+	review.ModerationReason = ""
+	review.ModeratedBy = nil
+	review.ModeratedAt = nil
+
+	if filtered.Verdict == filters.VerdictHold {
+		review.Status = models.ReviewStatusPending
+		review.ModerationReason = filterHoldReason(filtered)
+	}
+	return nil
+}
+
+// GetCommentModerationQueue godoc
+// @Summary Get the comment moderation queue
+// @Description Get comments in a moderation status, oldest first. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param status query string false "Comment status: pending (default), hidden or published"
+// @Success 200 {object} []models.Comment
+// @Failure 403 {object} map[string]string
+// @Router /moderation/comments [get]
+func GetCommentModerationQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.CommentStatusPending)
+	switch status {
+	case models.CommentStatusPending, models.CommentStatusPublished, models.CommentStatusHidden:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment status"})
+		return
+	}
+
+	var comments []models.Comment
+	if err := config.DB.Where("status = ?", status).Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// ApproveComment godoc
+// @Summary Approve a comment
+// @Description Publish a comment held for moderation. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Comment ID"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /moderation/comments/{id}/approve [post]
+func ApproveComment(c *gin.Context) {
+	moderateComment(c, models.CommentStatusPublished)
+}
+
+// RejectComment godoc
+// @Summary Reject a comment
+// @Description Hide a comment held for moderation. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Comment ID"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /moderation/comments/{id}/reject [post]
+func RejectComment(c *gin.Context) {
+	moderateComment(c, models.CommentStatusHidden)
+}
+
+func moderateComment(c *gin.Context, status string) {
+	moderatorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	commentID, ok := pathID(c, "id", "comment")
+	if !ok {
+		return
+	}
+
+	var comment models.Comment
+	if err := config.DB.First(&comment, commentID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	previous := comment.Status
+	err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Updates(map[string]interface{}{"status": status, "moderated_by": moderatorID}).Error; err != nil {
+			return err
+		}
+		if previous == status {
+			return nil
+		}
+		if err := notifyCommentModerated(tx, comment, moderatorID); err != nil {
+			return err
+		}
+		if previous == models.CommentStatusPublished || status != models.CommentStatusPublished {
+			return nil
+		}
+
+		// A held comment is only announced once it is approved.
+		var mentioned []models.User
+		if err := tx.Joins("JOIN mentions ON mentions.user_id = users.id AND mentions.deleted_at IS NULL").
+			Where("mentions.comment_id = ?", comment.ID).
+			Find(&mentioned).Error; err != nil {
+			return err
+		}
+		return notifyComment(tx, comment, mentioned)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if previous != models.CommentStatusPublished {
+		publishComment(comment)
+	}
+	c.JSON(http.StatusOK, comment)
+}
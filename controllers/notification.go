@@ -0,0 +1,389 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/events"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetNotifications godoc
+// @Summary Get my notifications
+// @Description Get the authenticated user's notifications, newest first, together with the number of unread ones
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} models.NotificationPage
+// @Failure 400 {object} map[string]string
+// @Router /notifications [get]
+func GetNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	p, ok := parsePage(c, sortNewest)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("notifications.user_id = ?", userID)
+	switch c.Query("unread") {
+	case "":
+	case "true", "1":
+		query = query.Where("notifications.read_at IS NULL")
+	case "false", "0":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unread must be true or false"})
+		return
+	}
+
+	var notifications []models.Notification
+	if err := keyset(query, "notifications", p).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.NotificationPage{Items: notifications}
+	if len(notifications) > p.Limit {
+		response.Items = notifications[:p.Limit]
+		last := response.Items[p.Limit-1]
+		response.NextCursor = utils.EncodeCursor(utils.Cursor{ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+	if response.Items == nil {
+		response.Items = []models.Notification{}
+	}
+
+	if err := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&response.UnreadCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark a notification as read
+// @Description Mark one of the authenticated user's notifications as read
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /notifications/{id}/read [post]
+func MarkNotificationRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	notificationID, ok := pathID(c, "id", "notification")
+	if !ok {
+		return
+	}
+
+	var notification models.Notification
+	if err := config.DB.Where("user_id = ?", userID).First(&notification, notificationID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all my notifications as read
+// @Description Mark every unread notification of the authenticated user as read
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]int64
+// @Router /notifications/read-all [post]
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	result := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked_read": result.RowsAffected})
+}
+
+// GetNotificationPreferences godoc
+// @Summary Get my notification preferences
+// @Description Get whether each notification type is turned on for the authenticated user
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]bool
+// @Router /notifications/preferences [get]
+func GetNotificationPreferences(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	respondNotificationPreferences(c, userID)
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary Update my notification preferences
+// @Description Turn notification types on or off for the authenticated user. Types left out of the body keep their setting.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param preferences body map[string]bool true "Enabled flag per notification type"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} map[string]interface{}
+// @Router /notifications/preferences [put]
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var input map[string]bool
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	var unknown []string
+	for notificationType := range input {
+		if !slices.Contains(models.NotificationTypes, notificationType) {
+			unknown = append(unknown, notificationType)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		fieldErrors := make([]utils.FieldError, len(unknown))
+		for i, notificationType := range unknown {
+			fieldErrors[i] = utils.FieldError{Field: notificationType, Message: "is not a notification type"}
+		}
+		utils.RespondValidationError(c, fieldErrors)
+		return
+	}
+
+	preferences := make([]models.NotificationPreference, 0, len(input))
+	for _, notificationType := range models.NotificationTypes {
+		if enabled, ok := input[notificationType]; ok {
+			preferences = append(preferences, models.NotificationPreference{UserID: userID, Type: notificationType, Enabled: enabled})
+		}
+	}
+
+	if len(preferences) > 0 {
+		if err := config.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).Create(&preferences).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	respondNotificationPreferences(c, userID)
+}
+
+func respondNotificationPreferences(c *gin.Context, userID uint) {
+	var preferences []models.NotificationPreference
+	if err := config.DB.Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make(map[string]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		response[notificationType] = true
+	}
+	for _, preference := range preferences {
+		response[preference.Type] = preference.Enabled
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// notify stores a notification for its recipient unless they turned its
+// type off, and pushes it to their stream. Nobody is notified about their
+// own actions.
+func notify(tx *gorm.DB, notification models.Notification) error {
+	if notification.UserID == 0 || notification.UserID == notification.ActorID {
+		return nil
+	}
+
+	var disabled int64
+	if err := tx.Model(&models.NotificationPreference{}).
+		Where("user_id = ? AND type = ? AND enabled = ?", notification.UserID, notification.Type, false).
+		Count(&disabled).Error; err != nil {
+		return err
+	}
+	if disabled > 0 {
+		return nil
+	}
+
+	if err := tx.Create(&notification).Error; err != nil {
+		return err
+	}
+	return queueEvent(tx, events.UserNotificationsTopic(notification.UserID), "notification", notification)
+}
+
+// notifyComment announces a newly published comment to the users it
+// mentions, the author of the comment it replies to and the author of the
+// review, each of them once.
+func notifyComment(tx *gorm.DB, comment models.Comment, mentioned []models.User) error {
+	if comment.Status != models.CommentStatusPublished {
+		return nil
+	}
+	if err := notifyMentions(tx, comment, mentioned); err != nil {
+		return err
+	}
+
+	notified := map[uint]bool{comment.UserID: true}
+	for _, user := range mentioned {
+		notified[user.ID] = true
+	}
+
+	reviewID, commentID := comment.ReviewID, comment.ID
+	if comment.ParentID != nil {
+		var parent models.Comment
+		if err := tx.Select("id", "user_id").First(&parent, *comment.ParentID).Error; err != nil {
+			return err
+		}
+		if !notified[parent.UserID] {
+			notified[parent.UserID] = true
+			if err := notify(tx, models.Notification{
+				UserID:    parent.UserID,
+				ActorID:   comment.UserID,
+				Type:      models.NotificationReply,
+				ReviewID:  &reviewID,
+				CommentID: &commentID,
+				Message:   "Someone replied to your comment",
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	var review models.Review
+	if err := tx.Select("id", "user_id").First(&review, comment.ReviewID).Error; err != nil {
+		return err
+	}
+	if notified[review.UserID] {
+		return nil
+	}
+	return notify(tx, models.Notification{
+		UserID:    review.UserID,
+		ActorID:   comment.UserID,
+		Type:      models.NotificationComment,
+		ReviewID:  &reviewID,
+		CommentID: &commentID,
+		Message:   "Someone commented on your review",
+	})
+}
+
+// notifyVote tells the author of a review that it received a new vote.
+// Changing an existing vote is not announced again.
+func notifyVote(tx *gorm.DB, review models.Review, vote models.ReviewVote) error {
+	message := "Someone found your review helpful"
+	if !vote.Helpful {
+		message = "Someone found your review not helpful"
+	}
+
+	reviewID := review.ID
+	return notify(tx, models.Notification{
+		UserID:   review.UserID,
+		ActorID:  vote.UserID,
+		Type:     models.NotificationVote,
+		ReviewID: &reviewID,
+		Message:  message,
+	})
+}
+
+// notifyReviewModerated tells the author of a review about a moderation
+// decision on it. moderatorID is 0 for automatic decisions.
+func notifyReviewModerated(tx *gorm.DB, review models.Review, moderatorID uint) error {
+	message := fmt.Sprintf("Your review was %s", review.Status)
+	if review.Status == models.ReviewStatusPublished {
+		message = "Your review was approved and is now published"
+	}
+	if review.ModerationReason != "" {
+		message += ": " + review.ModerationReason
+	}
+
+	reviewID := review.ID
+	return notify(tx, models.Notification{
+		UserID:   review.UserID,
+		ActorID:  moderatorID,
+		Type:     models.NotificationModeration,
+		ReviewID: &reviewID,
+		Message:  message,
+	})
+}
+
+// notifyCommentModerated tells the author of a comment about a moderation
+// decision on it.
+func notifyCommentModerated(tx *gorm.DB, comment models.Comment, moderatorID uint) error {
+	message := fmt.Sprintf("Your comment was %s", comment.Status)
+	if comment.Status == models.CommentStatusPublished {
+		message = "Your comment was approved and is now published"
+	}
+
+	reviewID, commentID := comment.ReviewID, comment.ID
+	return notify(tx, models.Notification{
+		UserID:    comment.UserID,
+		ActorID:   moderatorID,
+		Type:      models.NotificationModeration,
+		ReviewID:  &reviewID,
+		CommentID: &commentID,
+		Message:   message,
+	})
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	sortNewest  = "newest"
+	sortOldest  = "oldest"
+	sortHelpful = "helpful"
+
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	defaultCommentPreview = 3
+	maxCommentPreview     = 50
+)
+
+type page struct {
+	Limit  int
+	Sort   string
+	Cursor *utils.Cursor
+}
+
+// parsePage reads the limit, cursor and sort query parameters of a listing.
+// sorts holds the orders the listing supports, the first one being the
+// default. It writes the error response itself when a parameter is invalid.
+func parsePage(c *gin.Context, sorts ...string) (page, bool) {
+	limit, err := utils.ParseLimit(c.Query("limit"), defaultPageSize, maxPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return page{}, false
+	}
+
+	p := page{Limit: limit, Sort: sorts[0]}
+	if sort := c.Query("sort"); sort != "" {
+		valid := false
+		for _, allowed := range sorts {
+			valid = valid || sort == allowed
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort order"})
+			return page{}, false
+		}
+		p.Sort = sort
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return page{}, false
+		}
+		p.Cursor = &cursor
+	}
+
+	return p, true
+}
+
+// keyset orders a query on table by the page's sort and continues after its
+// cursor. It asks for one row more than the page size so the caller can
+// tell whether another page follows.
+func keyset(query *gorm.DB, table string, p page) *gorm.DB {
+	switch p.Sort {
+	case sortOldest:
+		if p.Cursor != nil {
+			query = query.Where(fmt.Sprintf("(%[1]s.created_at > ? OR (%[1]s.created_at = ? AND %[1]s.id > ?))", table),
+				p.Cursor.CreatedAt, p.Cursor.CreatedAt, p.Cursor.ID)
+		}
+		query = query.Order(table + ".created_at ASC").Order(table + ".id ASC")
+	case sortHelpful:
+		if p.Cursor != nil {
+			query = query.Where(fmt.Sprintf("(%[1]s.helpful_count < ? OR (%[1]s.helpful_count = ? AND %[1]s.id < ?))", table),
+				p.Cursor.Score, p.Cursor.Score, p.Cursor.ID)
+		}
+		query = query.Order(table + ".helpful_count DESC").Order(table + ".id DESC")
+	default:
+		if p.Cursor != nil {
+			query = query.Where(fmt.Sprintf("(%[1]s.created_at < ? OR (%[1]s.created_at = ? AND %[1]s.id < ?))", table),
+				p.Cursor.CreatedAt, p.Cursor.CreatedAt, p.Cursor.ID)
+		}
+		query = query.Order(table + ".created_at DESC").Order(table + ".id DESC")
+	}
+
+	return query.Limit(p.Limit + 1)
+}
+
+// respondPage answers one of the listings that returned a bare array before
+// they were paginated. Clients that pass format=page get the page object;
+// everyone else still gets the array of items, with the cursor of the next
+// page in the X-Next-Cursor header.
+func respondPage(c *gin.Context, page interface{}, items interface{}, nextCursor string) {
+	if c.Query("format") == "page" {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	if nextCursor != "" {
+		c.Header("X-Next-Cursor", nextCursor)
+	}
+	c.JSON(http.StatusOK, items)
+}
@@ -3,25 +3,41 @@ package controllers
 import (
 	"backend-vercel-phone-review/config"
 	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const topPointsLimit = 5
+
 // GetPhones godoc
 // @Summary Get all phones
-// @Description Get all phones
+// @Description Get all phones, optionally filtered and sorted by numeric specs. Bounds may carry a unit, such as min[display_size]=6.5in or max[weight]=0.2kg.
 // @Tags phones
 // @Accept  json
 // @Produce  json
+// @Param min[spec_key] query string false "Only phones whose spec is at least this value"
+// @Param max[spec_key] query string false "Only phones whose spec is at most this value"
+// @Param sort_spec query string false "Sort by this numeric spec, phones without a value last"
+// @Param order query string false "Sort order for sort_spec: asc or desc (default desc)"
 // @Success 200 {array} models.Phone
+// @Failure 400 {object} map[string]interface{}
 // @Router /phones [get]
 func GetPhones(c *gin.Context) {
 	var phones []models.Phone
 
-	if err := config.DB.Preload("Features").Preload("Reviews").Find(&phones).Error; err != nil {
+	query, ok := filterPhonesBySpecs(c, config.DB.Model(&models.Phone{}))
+	if !ok {
+		return
+	}
+
+	if err := query.Preload("Features").Preload("Reviews", publishedReviews).Find(&phones).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -31,7 +47,7 @@ func GetPhones(c *gin.Context) {
 
 // CreatePhone godoc
 // @Summary Create a new phone
-// @Description Create a new phone
+// @Description Create a new phone. Its brand is matched to an existing brand ignoring case and spacing, or created.
 // @Tags phones
 // @Accept  json
 // @Produce  json
@@ -41,13 +57,31 @@ func GetPhones(c *gin.Context) {
 // @Success 200 {object} map[string]string
 // @Router /phones [post]
 func CreatePhone(c *gin.Context) {
-	var input models.Phone
+	var input models.PhoneRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
+		return
+	}
+	if utils.Slugify(input.Brand) == "" {
+		utils.RespondValidationError(c, []utils.FieldError{{Field: "brand", Message: "must contain letters or digits"}})
 		return
 	}
 
-	if err := config.DB.Create(&input).Error; err != nil {
+	phone := models.Phone{
+		Name:  input.Name,
+		Brand: input.Brand,
+		Price: input.Price,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := resolveBrand(tx, &phone); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(&phone).Error; err != nil {
+			return err
+		}
+		return dispatchWebhooks(tx, models.WebhookEventPhoneCreated, phone)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -57,7 +91,7 @@ func CreatePhone(c *gin.Context) {
 
 // GetPhoneByID godoc
 // @Summary Get a phone by ID
-// @Description Get a phone by ID
+// @Description Get a phone by ID with its features, its published reviews, its typed specs grouped by category and its variants
 // @Tags phones
 // @Accept  json
 // @Produce  json
@@ -67,10 +101,13 @@ func CreatePhone(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /phones/{phone_id} [get]
 func GetPhoneByID(c *gin.Context) {
-	phoneID := c.Param("phone_id")
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
 
 	var phone models.Phone
-	if err := config.DB.Preload("Features").Preload("Reviews").First(&phone, phoneID).Error; err != nil {
+	if err := config.DB.Preload("Features").Preload("Reviews", publishedReviews).First(&phone, phoneID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
 		} else {
@@ -79,12 +116,26 @@ func GetPhoneByID(c *gin.Context) {
 		return
 	}
 
+	specs, err := loadPhoneSpecs(config.DB, phone.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	phone.Specs = specs
+
+	variants, err := loadVariants(config.DB, phone.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	phone.Variants = variants
+
 	c.JSON(http.StatusOK, phone)
 }
 
 // UpdatePhone godoc
 // @Summary Update a phone
-// @Description Update a phone
+// @Description Update a phone. Its brand is matched to an existing brand ignoring case and spacing, or created.
 // @Tags phones
 // @Accept  json
 // @Produce  json
@@ -97,9 +148,13 @@ func GetPhoneByID(c *gin.Context) {
 func UpdatePhone(c *gin.Context) {
 	phoneID := c.Param("phone_id")
 
-	var input models.Phone
+	var input models.PhoneRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
+		return
+	}
+	if utils.Slugify(input.Brand) == "" {
+		utils.RespondValidationError(c, []utils.FieldError{{Field: "brand", Message: "must contain letters or digits"}})
 		return
 	}
 
@@ -110,14 +165,48 @@ func UpdatePhone(c *gin.Context) {
 		return
 	}
 
-	input.ID = uint(id)
+	var phone models.Phone
+	if err := config.DB.First(&phone, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	change := priceChange(phone.Price, input.Price)
+	phone.Name = input.Name
+	phone.Brand = input.Brand
+	phone.Price = input.Price
 
-	if err := config.DB.Save(&input).Error; err != nil {
+	userID, _ := currentUserID(c)
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := resolveBrand(tx, &phone); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&phone).Error; err != nil {
+			return err
+		}
+		if err := dispatchWebhooks(tx, models.WebhookEventPhoneUpdated, phone); err != nil {
+			return err
+		}
+		if change == "" {
+			return nil
+		}
+		return queuePhoneFollowers(tx, phoneFollowersJob{
+			Update:  phoneUpdatePrice,
+			PhoneID: phone.ID,
+			ActorID: userID,
+			Detail:  change,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, input)
+	c.JSON(http.StatusOK, phone)
 }
 
 // DeletePhone godoc
@@ -154,10 +243,153 @@ func DeletePhone(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Delete(&phone).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&phone).Error; err != nil {
+			return err
+		}
+		return dispatchWebhooks(tx, models.WebhookEventPhoneDeleted, phone)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "phone deleted successfully"})
 }
+
+// GetPhoneStats godoc
+// @Summary Get rating statistics of a phone
+// @Description Get the overall rating, per-dimension sub-rating averages and most mentioned pros and cons of a phone. Reviews of its variants count towards the phone and are also broken down per variant.
+// @Tags phones
+// @Accept  json
+// @Produce  json
+// @Param phone_id path int true "Phone ID"
+// @Success 200 {object} models.PhoneStats
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /phones/{phone_id}/stats [get]
+func GetPhoneStats(c *gin.Context) {
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	stats, err := phoneStats(config.DB, phone.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// phoneStats aggregates the published reviews of a phone.
+func phoneStats(db *gorm.DB, phoneID uint) (models.PhoneStats, error) {
+	stats := models.PhoneStats{PhoneID: phoneID}
+
+	var overall struct {
+		Count   int64
+		Average float64
+	}
+	if err := db.Model(&models.Review{}).Scopes(publishedReviews).
+		Select("COUNT(*) AS count, COALESCE(AVG(rating), 0) AS average").
+		Where("phone_id = ?", phoneID).
+		Scan(&overall).Error; err != nil {
+		return stats, err
+	}
+	stats.ReviewCount = overall.Count
+	stats.AverageRating = overall.Average
+
+	var dimensions []models.DimensionStats
+	if err := db.Model(&models.SubRating{}).
+		Select("sub_ratings.dimension, AVG(sub_ratings.score) AS average, COUNT(*) AS count").
+		Joins("JOIN reviews ON reviews.id = sub_ratings.review_id AND reviews.deleted_at IS NULL").
+		Where("reviews.phone_id = ? AND reviews.status = ?", phoneID, models.ReviewStatusPublished).
+		Group("sub_ratings.dimension").
+		Scan(&dimensions).Error; err != nil {
+		return stats, err
+	}
+
+	// Report every configured dimension, even those nobody has scored yet
+	byDimension := make(map[string]models.DimensionStats)
+	for _, dimension := range dimensions {
+		byDimension[dimension.Dimension] = dimension
+	}
+	for _, dimension := range config.RatingDimensions() {
+		entry, ok := byDimension[dimension]
+		if !ok {
+			entry = models.DimensionStats{Dimension: dimension}
+		}
+		stats.Dimensions = append(stats.Dimensions, entry)
+	}
+
+	// Variant reviews count towards the phone above and are broken down here
+	stats.Variants = []models.VariantStats{}
+	if err := db.Model(&models.Review{}).Scopes(publishedReviews).
+		Select("reviews.variant_id, variants.name, COUNT(*) AS review_count, AVG(reviews.rating) AS average_rating").
+		Joins("JOIN variants ON variants.id = reviews.variant_id AND variants.deleted_at IS NULL").
+		Where("reviews.phone_id = ?", phoneID).
+		Group("reviews.variant_id, variants.name").
+		Order("reviews.variant_id ASC").
+		Scan(&stats.Variants).Error; err != nil {
+		return stats, err
+	}
+
+	var reviews []models.Review
+	if err := db.Scopes(publishedReviews).Select("pros", "cons").Where("phone_id = ?", phoneID).Find(&reviews).Error; err != nil {
+		return stats, err
+	}
+
+	var pros, cons [][]string
+	for _, review := range reviews {
+		pros = append(pros, review.Pros)
+		cons = append(cons, review.Cons)
+	}
+	stats.TopPros = topPoints(pros)
+	stats.TopCons = topPoints(cons)
+
+	return stats, nil
+}
+
+// topPoints counts how many reviews mention each pro or con, ignoring case,
+// and returns the most frequent ones.
+func topPoints(lists [][]string) []models.PointCount {
+	counts := make(map[string]*models.PointCount)
+	var ordered []*models.PointCount
+	for _, points := range lists {
+		for _, point := range points {
+			key := strings.ToLower(strings.TrimSpace(point))
+			if key == "" {
+				continue
+			}
+			if entry, ok := counts[key]; ok {
+				entry.Count++
+				continue
+			}
+			entry := &models.PointCount{Text: point, Count: 1}
+			counts[key] = entry
+			ordered = append(ordered, entry)
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Count > ordered[j].Count
+	})
+
+	top := []models.PointCount{}
+	for i := 0; i < len(ordered) && i < topPointsLimit; i++ {
+		top = append(top, *ordered[i])
+	}
+	return top
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"backend-vercel-phone-review/config"
 	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -36,12 +37,16 @@ type ReviewResponse struct {
 // @Security ApiKeyAuth
 // @Param id path int true "User ID"
 // @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
 // @Router /users/{id} [get]
 func GetUser(c *gin.Context) {
 	var user models.User
-	userID := c.Param("id")
+	userID, ok := pathID(c, "id", "user")
+	if !ok {
+		return
+	}
 
-	if err := config.DB.Preload("Profile").Preload("Reviews").First(&user, userID).Error; err != nil {
+	if err := config.DB.Preload("Profile").Preload("Reviews", publishedReviews).First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -79,16 +84,20 @@ func GetUser(c *gin.Context) {
 // @Param id path int true "User ID"
 // @Param profile body models.Profile true "Profile"
 // @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
 // @Router /users/{id}/profile [put]
 func UpdateProfile(c *gin.Context) {
 	var input struct {
-		Bio      string `json:"bio"`
-		FullName string `json:"full_name"`
+		Bio      string `json:"bio" binding:"max=1000,nohtml"`
+		FullName string `json:"full_name" binding:"max=100,nohtml"`
+	}
+	userID, ok := pathID(c, "id", "user")
+	if !ok {
+		return
 	}
-	userID := c.Param("id")
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
 		return
 	}
 
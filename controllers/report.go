@@ -0,0 +1,479 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportReview godoc
+// @Summary Report a review
+// @Description Flag a review as spam, abuse or otherwise inappropriate. Each user can report a review once.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Review ID"
+// @Param report body models.ReportRequest true "Report"
+// @Success 200 {object} models.Report
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /reviews/{id}/reports [post]
+func ReportReview(c *gin.Context) {
+	review, found := findReview(c)
+	if !found {
+		return
+	}
+
+	createReport(c, models.ReportTargetReview, review.ID, review.UserID)
+}
+
+// ReportComment godoc
+// @Summary Report a comment
+// @Description Flag a comment as spam, abuse or otherwise inappropriate. Each user can report a comment once.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Comment ID"
+// @Param report body models.ReportRequest true "Report"
+// @Success 200 {object} models.Report
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /comments/{id}/reports [post]
+func ReportComment(c *gin.Context) {
+	commentID, ok := pathID(c, "id", "comment")
+	if !ok {
+		return
+	}
+
+	var comment models.Comment
+	if err := config.DB.Scopes(publishedComments).First(&comment, commentID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	createReport(c, models.ReportTargetComment, comment.ID, comment.UserID)
+}
+
+func createReport(c *gin.Context, targetType string, targetID, authorID uint) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var input models.ReportRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	if authorID == userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("you cannot report your own %s", targetType)})
+		return
+	}
+
+	report := models.Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		ReporterID: userID,
+		Reason:     input.Reason,
+		Details:    input.Details,
+		Status:     models.ReportStatusOpen,
+	}
+
+	err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		return autoHideReported(tx, targetType, targetID)
+	})
+	if err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("you have already reported this %s", targetType)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// autoHideReported hides a review or comment once its open reports reach
+// the configured threshold. Auto-hidden reviews and comments carry no
+// moderator, which lets dismissing the reports publish them again.
+func autoHideReported(tx *gorm.DB, targetType string, targetID uint) error {
+	threshold := config.ReportHideThreshold()
+	if threshold == 0 {
+		return nil
+	}
+
+	var open int64
+	if err := tx.Model(&models.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.ReportStatusOpen).
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open < threshold {
+		return nil
+	}
+
+	if targetType == models.ReportTargetComment {
+		result := tx.Model(&models.Comment{}).
+			Where("id = ? AND status = ?", targetID, models.CommentStatusPublished).
+			Updates(map[string]interface{}{"status": models.CommentStatusHidden, "moderated_by": nil})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return notifyReportedComment(tx, targetID, 0)
+	}
+
+	var review models.Review
+	if err := tx.First(&review, targetID).Error; err != nil {
+		return err
+	}
+	if review.Status != models.ReviewStatusPublished {
+		return nil
+	}
+
+	previous := review
+	now := time.Now()
+	review.Status = models.ReviewStatusHidden
+	review.ModerationReason = fmt.Sprintf("hidden automatically after %d reports", open)
+	review.ModeratedBy = nil
+	review.ModeratedAt = &now
+
+	updated, err := updateReviewStatus(tx, review, previous)
+	if err != nil || !updated {
+		return err
+	}
+	return notifyReviewModerated(tx, review, 0)
+}
+
+// notifyReportedComment tells the author of a comment that it was hidden
+// because of reports.
+func notifyReportedComment(tx *gorm.DB, commentID, moderatorID uint) error {
+	var comment models.Comment
+	if err := tx.First(&comment, commentID).Error; err != nil {
+		return err
+	}
+	return notifyCommentModerated(tx, comment, moderatorID)
+}
+
+// GetReportQueue godoc
+// @Summary Get reported content awaiting triage
+// @Description Get every review and comment with open reports, most reported first. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param type query string false "Only reports on this target type: review or comment"
+// @Success 200 {object} []models.ReportTriage
+// @Failure 403 {object} map[string]string
+// @Router /moderation/reports [get]
+func GetReportQueue(c *gin.Context) {
+	query := config.DB.Where("status = ?", models.ReportStatusOpen).Order("created_at ASC")
+	switch targetType := c.Query("type"); targetType {
+	case "":
+	case models.ReportTargetReview, models.ReportTargetComment:
+		query = query.Where("target_type = ?", targetType)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report target type"})
+		return
+	}
+
+	var reports []models.Report
+	if err := query.Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type target struct {
+		Type string
+		ID   uint
+	}
+	byTarget := make(map[target]*models.ReportTriage)
+	var items []*models.ReportTriage
+	for _, report := range reports {
+		key := target{report.TargetType, report.TargetID}
+		item, ok := byTarget[key]
+		if !ok {
+			item = &models.ReportTriage{
+				TargetType:    report.TargetType,
+				TargetID:      report.TargetID,
+				Reasons:       make(map[string]int),
+				FirstReportAt: report.CreatedAt,
+			}
+			byTarget[key] = item
+			items = append(items, item)
+		}
+		item.OpenReports++
+		item.Reasons[report.Reason]++
+		item.LatestReportAt = report.CreatedAt
+	}
+
+	var commentIDs, reviewIDs []uint
+	for _, item := range items {
+		if item.TargetType == models.ReportTargetComment {
+			commentIDs = append(commentIDs, item.TargetID)
+		} else {
+			reviewIDs = append(reviewIDs, item.TargetID)
+		}
+	}
+
+	if len(commentIDs) > 0 {
+		var comments []models.Comment
+		if err := config.DB.Unscoped().Where("id IN ?", commentIDs).Find(&comments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, comment := range comments {
+			item := byTarget[target{models.ReportTargetComment, comment.ID}]
+			item.Content = comment.Content
+			item.TargetStatus = comment.Status
+		}
+	}
+
+	if len(reviewIDs) > 0 {
+		var reviews []models.Review
+		if err := config.DB.Unscoped().Where("id IN ?", reviewIDs).Find(&reviews).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, review := range reviews {
+			item := byTarget[target{models.ReportTargetReview, review.ID}]
+			item.Content = review.Content
+			item.TargetStatus = review.Status
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].OpenReports > items[j].OpenReports
+	})
+
+	response := []models.ReportTriage{}
+	for _, item := range items {
+		response = append(response, *item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DismissReports godoc
+// @Summary Dismiss the reports on a review or comment
+// @Description Close every open report on the target as unfounded and restore it if it was hidden automatically. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param target_type path string true "reviews or comments"
+// @Param id path int true "Review or comment ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /moderation/reports/{target_type}/{id}/dismiss [post]
+func DismissReports(c *gin.Context) {
+	targetType, targetID, ok := reportTarget(c)
+	if !ok {
+		return
+	}
+
+	var (
+		comment  models.Comment
+		review   models.Review
+		previous models.Review
+		restored bool
+	)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := closeReports(tx, targetType, targetID, models.ReportStatusDismissed); err != nil {
+			return err
+		}
+
+		// The reports are closed even when their target has been deleted
+		if targetType == models.ReportTargetComment {
+			if err := tx.First(&comment, targetID).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return nil
+				}
+				return err
+			}
+			if comment.Status != models.CommentStatusHidden || comment.ModeratedBy != nil {
+				return nil
+			}
+			result := tx.Model(&comment).
+				Where("status = ?", models.CommentStatusHidden).
+				Update("status", models.CommentStatusPublished)
+			restored = result.RowsAffected > 0
+			return result.Error
+		}
+
+		if err := tx.First(&review, targetID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+		if review.Status != models.ReviewStatusHidden || review.ModeratedBy != nil {
+			return nil
+		}
+
+		previous = review
+		review.Status = models.ReviewStatusPublished
+		review.ModerationReason = ""
+		review.ModeratedAt = nil
+
+		var err error
+		restored, err = updateReviewStatus(tx, review, previous)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if restored {
+		if targetType == models.ReportTargetComment {
+			publishComment(comment)
+		} else {
+			announceReview(review, &previous)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "reports dismissed"})
+}
+
+// UpholdReports godoc
+// @Summary Uphold the reports on a review or comment
+// @Description Close every open report on the target as valid and hide it. Moderators only.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param target_type path string true "reviews or comments"
+// @Param id path int true "Review or comment ID"
+// @Param moderation body models.ModerationRequest true "Reason"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /moderation/reports/{target_type}/{id}/uphold [post]
+func UpholdReports(c *gin.Context) {
+	moderatorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	targetType, targetID, ok := reportTarget(c)
+	if !ok {
+		return
+	}
+
+	var input models.ModerationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	err := withEvents(func(tx *gorm.DB) error {
+		if err := closeReports(tx, targetType, targetID, models.ReportStatusUpheld); err != nil {
+			return err
+		}
+
+		// The reports are closed even when their target has been deleted,
+		// and only a change of status is notified
+		if targetType == models.ReportTargetComment {
+			result := tx.Model(&models.Comment{}).
+				Where("id = ? AND status <> ?", targetID, models.CommentStatusHidden).
+				Updates(map[string]interface{}{"status": models.CommentStatusHidden, "moderated_by": moderatorID})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return notifyReportedComment(tx, targetID, moderatorID)
+		}
+
+		var review models.Review
+		if err := tx.First(&review, targetID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+
+		previous := review
+		now := time.Now()
+		review.Status = models.ReviewStatusHidden
+		review.ModerationReason = input.Reason
+		review.ModeratedBy = &moderatorID
+		review.ModeratedAt = &now
+
+		updated, err := updateReviewStatus(tx, review, previous)
+		if err != nil || !updated {
+			return err
+		}
+		return notifyReviewModerated(tx, review, moderatorID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "reports upheld"})
+}
+
+// reportTarget reads the target_type and id path parameters of the report
+// triage routes, writing the error response itself when they are invalid.
+func reportTarget(c *gin.Context) (string, uint, bool) {
+	var targetType string
+	switch c.Param("target_type") {
+	case "reviews":
+		targetType = models.ReportTargetReview
+	case "comments":
+		targetType = models.ReportTargetComment
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report target type"})
+		return "", 0, false
+	}
+
+	targetID := utils.StringToUint(c.Param("id"))
+	if targetID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target ID"})
+		return "", 0, false
+	}
+
+	var open int64
+	if err := config.DB.Model(&models.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.ReportStatusOpen).
+		Count(&open).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", 0, false
+	}
+	if open == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no open reports for this target"})
+		return "", 0, false
+	}
+
+	return targetType, targetID, true
+}
+
+func closeReports(tx *gorm.DB, targetType string, targetID uint, status string) error {
+	return tx.Model(&models.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.ReportStatusOpen).
+		Update("status", status).Error
+}
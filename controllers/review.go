@@ -3,15 +3,21 @@ package controllers
 import (
 	"backend-vercel-phone-review/config"
 	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateReview godoc
 // @Summary Create a new review
-// @Description Create a new review
+// @Description Create a new review. A user can review each phone only once; a second review answers 409 with a link to the existing one.
 // @Tags reviews
 // @Accept json
 // @Produce json
@@ -19,61 +25,332 @@ import (
 // @Security ApiKeyAuth
 // @Param review body models.Review true "Review"
 // @Success 200 {object} models.Review
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Router /reviews [post]
 func CreateReview(c *gin.Context) {
-	var review models.Review
-	if err := c.ShouldBindJSON(&review); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var input models.Review
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	if fields := validateSubRatings(input.SubRatings); len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+
+	// Only the fields a reviewer writes are taken from the request, so a
+	// client sent ID or status cannot reach the row that gets saved
+	review := models.Review{
+		PhoneID:    input.PhoneID,
+		VariantID:  input.VariantID,
+		Rating:     input.Rating,
+		Content:    input.Content,
+		Language:   strings.ToLower(input.Language),
+		Pros:       cleanPoints(input.Pros),
+		Cons:       cleanPoints(input.Cons),
+		SubRatings: input.SubRatings,
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, review.PhoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	fields, err := validateReviewVariant(phone.ID, review.VariantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
 		return
 	}
 
-	if err := config.DB.Create(&review).Error; err != nil {
+	existingReview, err := findUserReview(userID, review.PhoneID)
+	if err != nil && err != gorm.ErrRecordNotFound {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if err == nil && !existingReview.DeletedAt.Valid {
+		respondDuplicateReview(c, existingReview)
+		return
+	}
 
+	// A review the user deleted earlier still holds the unique
+	// (user_id, phone_id) slot, so it is brought back instead
+	if err == nil {
+		review.ID = existingReview.ID
+		review.CreatedAt = existingReview.CreatedAt
+		review.DeletedAt = existingReview.DeletedAt
+	}
+	review.UserID = userID
+
+	subRatings := review.SubRatings
+	if subRatings == nil {
+		subRatings = []models.SubRating{}
+	}
+	if !screenReview(c, &review, nil) {
+		return
+	}
+
+	if err := saveReview(config.DB, &review, subRatings, nil); err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			c.JSON(http.StatusConflict, gin.H{"error": "you have already reviewed this phone"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Reload so the response carries database maintained columns
+	if err := config.DB.Preload("SubRatings").First(&review, review.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	announceReview(review, nil)
 	c.JSON(http.StatusOK, review)
 }
 
+// UpsertMyReview godoc
+// @Summary Create or replace my review of a phone
+// @Description Create the authenticated user's review of a phone, or replace it if one already exists
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param phone_id path int true "Phone ID"
+// @Param review body models.Review true "Review"
+// @Success 200 {object} models.Review
+// @Success 201 {object} models.Review
+// @Failure 404 {object} map[string]string
+// @Router /phones/{phone_id}/my-review [put]
+func UpsertMyReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	phoneID, err := strconv.ParseUint(c.Param("phone_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid phone ID"})
+		return
+	}
+
+	var input models.Review
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	if fields := validateSubRatings(input.SubRatings); len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	fields, err := validateReviewVariant(phone.ID, input.VariantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+
+	review, err := findUserReview(userID, phone.ID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if err == gorm.ErrRecordNotFound || review.DeletedAt.Valid {
+		status = http.StatusCreated
+	}
+	if err == gorm.ErrRecordNotFound {
+		review = models.Review{PhoneID: phone.ID, UserID: userID}
+	}
+
+	var previous *models.Review
+	if status == http.StatusOK {
+		snapshot := review
+		previous = &snapshot
+	}
+
+	review.VariantID = input.VariantID
+	review.Rating = input.Rating
+	review.Content = input.Content
+	review.Language = strings.ToLower(input.Language)
+	review.Pros = cleanPoints(input.Pros)
+	review.Cons = cleanPoints(input.Cons)
+
+	subRatings := input.SubRatings
+	if status == http.StatusCreated && subRatings == nil {
+		subRatings = []models.SubRating{}
+	}
+	if subRatings != nil {
+		review.SubRatings = subRatings
+	}
+	if !screenReview(c, &review, previous) {
+		return
+	}
+
+	if err := saveReview(config.DB, &review, subRatings, previous); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Preload("SubRatings").First(&review, review.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	announceReview(review, previous)
+	c.JSON(status, review)
+}
+
 // GetReviews godoc
-// @Summary Get reviews by phone ID
-// @Description Get reviews by phone ID
+// @Summary Get reviews of a phone
+// @Description Get a page of published reviews of a phone, each with its first comments
 // @Tags reviews
 // @Accept json
 // @Produce json
 // @Param phone_id path int true "Phone ID"
-// @Success 200 {object} []models.Review
-// @Router /reviews/{phone_id} [get]
+// @Param rating query int false "Only reviews with this rating"
+// @Param min_rating query int false "Only reviews rated at least this"
+// @Param max_rating query int false "Only reviews rated at most this"
+// @Param verified query bool false "Only verified (true) or unverified (false) reviews"
+// @Param variant_id query int false "Only reviews of this variant"
+// @Param language query string false "Only reviews in this language, e.g. en"
+// @Param from query string false "Only reviews written on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only reviews written on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param sort query string false "Sort order: newest (default), oldest or helpful"
+// @Param limit query int false "Page size, at most 100"
+// @Param cursor query string false "next_cursor of the previous page, or the X-Next-Cursor header"
+// @Param comments query int false "Number of top-level comments included per review, 3 by default"
+// @Param format query string false "page to get a models.ReviewPage instead of an array"
+// @Success 200 {object} []models.Review "The next cursor is in the X-Next-Cursor header"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /phones/{phone_id}/reviews [get]
 func GetReviews(c *gin.Context) {
-	phoneID := c.Param("phone_id")
-	var reviews []models.Review
-	if err := config.DB.Where("phone_id = ?", phoneID).Preload("Comments").Find(&reviews).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, reviews)
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	listReviews(c, config.DB.Where("reviews.phone_id = ?", phone.ID))
 }
 
 // GetAllReviews godoc
 // @Summary Get all reviews
-// @Description Get all reviews without authentication
+// @Description Get a page of published reviews without authentication, each with its first comments
 // @Tags reviews
 // @Accept json
 // @Produce json
-// @Success 200 {object} []models.Review
+// @Param rating query int false "Only reviews with this rating"
+// @Param min_rating query int false "Only reviews rated at least this"
+// @Param max_rating query int false "Only reviews rated at most this"
+// @Param verified query bool false "Only verified (true) or unverified (false) reviews"
+// @Param variant_id query int false "Only reviews of this variant"
+// @Param language query string false "Only reviews in this language, e.g. en"
+// @Param from query string false "Only reviews written on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only reviews written on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param sort query string false "Sort order: newest (default), oldest or helpful"
+// @Param limit query int false "Page size, at most 100"
+// @Param cursor query string false "next_cursor of the previous page, or the X-Next-Cursor header"
+// @Param comments query int false "Number of top-level comments included per review, 3 by default"
+// @Param format query string false "page to get a models.ReviewPage instead of an array"
+// @Success 200 {object} []models.Review "The next cursor is in the X-Next-Cursor header"
 // @Router /reviews [get]
 func GetAllReviews(c *gin.Context) {
+	listReviews(c, config.DB)
+}
+
+// listReviews answers a review listing with one page of the published
+// reviews matched by query and the filters in the query string.
+func listReviews(c *gin.Context, query *gorm.DB) {
+	p, ok := parsePage(c, sortNewest, sortOldest, sortHelpful)
+	if !ok {
+		return
+	}
+
+	query, err := filterReviews(c, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previewSize := defaultCommentPreview
+	if raw := c.Query("comments"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 0 || size > maxCommentPreview {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("comments must be between 0 and %d", maxCommentPreview)})
+			return
+		}
+		previewSize = size
+	}
+
 	var reviews []models.Review
-	if err := config.DB.Preload("Comments").Find(&reviews).Error; err != nil {
+	if err := keyset(query.Scopes(publishedReviews), "reviews", p).Preload("SubRatings").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.ReviewPage{Items: reviews}
+	if len(reviews) > p.Limit {
+		response.Items = reviews[:p.Limit]
+		last := response.Items[p.Limit-1]
+		response.NextCursor = utils.EncodeCursor(utils.Cursor{ID: last.ID, CreatedAt: last.CreatedAt, Score: last.HelpfulCount})
+	}
+
+	if err := loadCommentPreviews(response.Items, previewSize); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, reviews)
+
+	respondPage(c, response, response.Items, response.NextCursor)
 }
 
 // UpdateReview godoc
 // @Summary Update a review
-// @Description Update a review
+// @Description Update a review. Only its author, a moderator or an admin may do so.
 // @Tags reviews
 // @Accept json
 // @Produce json
@@ -82,18 +359,28 @@ func GetAllReviews(c *gin.Context) {
 // @Param id path int true "Review ID"
 // @Param review body models.Review true "Review"
 // @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /reviews/{id} [put]
 func UpdateReview(c *gin.Context) {
-	reviewID := c.Param("id")
+	reviewID, ok := pathID(c, "id", "review")
+	if !ok {
+		return
+	}
 
 	var updatedReview models.Review
 	if err := c.ShouldBindJSON(&updatedReview); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	if fields := validateSubRatings(updatedReview.SubRatings); len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
 		return
 	}
 
 	var existingReview models.Review
-	result := config.DB.First(&existingReview, reviewID)
+	result := config.DB.Preload("SubRatings").First(&existingReview, reviewID)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
@@ -103,20 +390,47 @@ func UpdateReview(c *gin.Context) {
 		return
 	}
 
+	if !requireAuthor(c, existingReview.UserID, "reviews") {
+		return
+	}
+
+	fields, err := validateReviewVariant(existingReview.PhoneID, updatedReview.VariantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+
+	previous := existingReview
+	existingReview.VariantID = updatedReview.VariantID
 	existingReview.Rating = updatedReview.Rating
 	existingReview.Content = updatedReview.Content
+	existingReview.Language = strings.ToLower(updatedReview.Language)
+	existingReview.Pros = cleanPoints(updatedReview.Pros)
+	existingReview.Cons = cleanPoints(updatedReview.Cons)
+	if updatedReview.SubRatings != nil {
+		existingReview.SubRatings = updatedReview.SubRatings
+	}
+
+	if !screenReview(c, &existingReview, &previous) {
+		return
+	}
 
-	if err := config.DB.Save(&existingReview).Error; err != nil {
+	if err := saveReview(config.DB, &existingReview, updatedReview.SubRatings, &previous); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	announceReview(existingReview, &previous)
 	c.JSON(http.StatusOK, existingReview)
 }
 
 // DeleteReview godoc
 // @Summary Delete a review
-// @Description Delete a review
+// @Description Delete a review. Only its author, a moderator or an admin may do so.
 // @Tags reviews
 // @Accept json
 // @Produce json
@@ -124,9 +438,14 @@ func UpdateReview(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Param id path int true "Review ID"
 // @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /reviews/{id} [delete]
 func DeleteReview(c *gin.Context) {
-	reviewID := c.Param("id")
+	reviewID, ok := pathID(c, "id", "review")
+	if !ok {
+		return
+	}
 
 	var existingReview models.Review
 	result := config.DB.First(&existingReview, reviewID)
@@ -139,7 +458,20 @@ func DeleteReview(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Delete(&existingReview).Error; err != nil {
+	if !requireAuthor(c, existingReview.UserID, "reviews") {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&existingReview).Error; err != nil {
+			return err
+		}
+		if existingReview.Status != models.ReviewStatusPublished {
+			return nil
+		}
+		return dispatchWebhooks(tx, models.WebhookEventReviewDeleted, existingReview)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -155,13 +487,17 @@ func DeleteReview(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Review ID"
 // @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
 // @Router /reviews/{id} [get]
 func GetReviewByID(c *gin.Context) {
-	reviewID := c.Param("id")
+	reviewID, ok := pathID(c, "id", "review")
+	if !ok {
+		return
+	}
 	var review models.Review
 
 	// Retrieve the review from the database
-	if err := config.DB.Preload("Comments").First(&review, reviewID).Error; err != nil {
+	if err := config.DB.Scopes(publishedReviews).Preload("SubRatings").Preload("Comments", publishedComments).First(&review, reviewID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 		} else {
@@ -170,6 +506,211 @@ func GetReviewByID(c *gin.Context) {
 		return
 	}
 
+	if err := renderMentions(review.Comments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Return the review details
 	c.JSON(http.StatusOK, review)
 }
+
+// filterReviews narrows a review query by the rating, verified, variant_id,
+// language, from and to query parameters.
+func filterReviews(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	for _, filter := range []struct{ param, condition string }{
+		{"rating", "reviews.rating = ?"},
+		{"min_rating", "reviews.rating >= ?"},
+		{"max_rating", "reviews.rating <= ?"},
+	} {
+		param := filter.param
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		rating, err := strconv.Atoi(raw)
+		if err != nil || rating < utils.MinRating || rating > utils.MaxRating {
+			return nil, fmt.Errorf("%s must be between %d and %d", param, utils.MinRating, utils.MaxRating)
+		}
+		query = query.Where(filter.condition, rating)
+	}
+
+	if raw := c.Query("verified"); raw != "" {
+		verified, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("verified must be true or false")
+		}
+		query = query.Where("reviews.verified = ?", verified)
+	}
+
+	if raw := c.Query("variant_id"); raw != "" {
+		variantID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("variant_id must be a variant ID")
+		}
+		query = query.Where("reviews.variant_id = ?", variantID)
+	}
+
+	if language := c.Query("language"); language != "" {
+		query = query.Where("reviews.language = ?", strings.ToLower(language))
+	}
+
+	if raw := c.Query("from"); raw != "" {
+		from, _, err := parseDate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		}
+		query = query.Where("reviews.created_at >= ?", from)
+	}
+
+	if raw := c.Query("to"); raw != "" {
+		to, dateOnly, err := parseDate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		}
+		// A bare date covers the whole day
+		if dateOnly {
+			query = query.Where("reviews.created_at < ?", to.AddDate(0, 0, 1))
+		} else {
+			query = query.Where("reviews.created_at <= ?", to)
+		}
+	}
+
+	return query, nil
+}
+
+// parseDate accepts either a calendar date or an RFC 3339 timestamp and
+// reports which of the two it was given.
+func parseDate(raw string) (time.Time, bool, error) {
+	if date, err := time.ParseInLocation(time.DateOnly, raw, time.Local); err == nil {
+		return date, true, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, raw)
+	return timestamp, false, err
+}
+
+// findUserReview looks up the review a user wrote for a phone, including
+// one that was soft deleted.
+func findUserReview(userID, phoneID uint) (models.Review, error) {
+	var review models.Review
+	err := config.DB.Unscoped().Preload("SubRatings").
+		Where("user_id = ? AND phone_id = ?", userID, phoneID).
+		First(&review).Error
+	return review, err
+}
+
+func respondDuplicateReview(c *gin.Context, review models.Review) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":     "you have already reviewed this phone",
+		"review_id": review.ID,
+		"link":      fmt.Sprintf("/api/v1/reviews/%d", review.ID),
+	})
+}
+
+// saveReview writes a review and, when subRatings is not nil, replaces its
+// sub-ratings with them. A soft deleted review is restored on save, without
+// the votes it had. When previous holds the review as it was before an edit
+// that changed it, the review is marked as edited and both versions are
+// kept as revisions.
+func saveReview(db *gorm.DB, review *models.Review, subRatings []models.SubRating, previous *models.Review) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		edited := previous != nil && reviewChanged(*previous, *review)
+		if edited {
+			now := time.Now()
+			review.Edited = true
+			review.EditedAt = &now
+		}
+
+		restored := review.DeletedAt.Valid
+		review.DeletedAt = gorm.DeletedAt{}
+		if err := tx.Unscoped().Omit(clause.Associations).Save(review).Error; err != nil {
+			return err
+		}
+
+		// A restored review starts over without the votes of the one that
+		// was deleted
+		if restored {
+			if err := tx.Unscoped().Where("review_id = ?", review.ID).Delete(&models.ReviewVote{}).Error; err != nil {
+				return err
+			}
+			if err := recountVotes(tx, review.ID); err != nil {
+				return err
+			}
+		}
+
+		if edited {
+			if err := recordRevisions(tx, *previous, *review); err != nil {
+				return err
+			}
+		}
+
+		if err := queueReviewWebhooks(tx, *review, previous); err != nil {
+			return err
+		}
+		if err := queueReviewFollowers(tx, *review, previous); err != nil {
+			return err
+		}
+
+		if subRatings == nil {
+			return nil
+		}
+
+		if err := tx.Unscoped().Where("review_id = ?", review.ID).Delete(&models.SubRating{}).Error; err != nil {
+			return err
+		}
+
+		for i := range subRatings {
+			subRatings[i].ID = 0
+			subRatings[i].ReviewID = review.ID
+		}
+		if len(subRatings) > 0 {
+			if err := tx.Create(&subRatings).Error; err != nil {
+				return err
+			}
+		}
+		review.SubRatings = subRatings
+		return nil
+	})
+}
+
+// cleanPoints trims pros or cons and drops empty and repeated entries.
+func cleanPoints(points []string) []string {
+	cleaned := []string{}
+	seen := make(map[string]bool)
+	for _, point := range points {
+		point = strings.TrimSpace(point)
+		key := strings.ToLower(point)
+		if point == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, point)
+	}
+	return cleaned
+}
+
+// validateSubRatings checks that every sub-rating targets a configured
+// dimension at most once. Score bounds are enforced by the binding tags.
+func validateSubRatings(subRatings []models.SubRating) []utils.FieldError {
+	allowed := make(map[string]bool)
+	for _, dimension := range config.RatingDimensions() {
+		allowed[dimension] = true
+	}
+
+	var fields []utils.FieldError
+	seen := make(map[string]bool)
+	for i := range subRatings {
+		field := fmt.Sprintf("sub_ratings[%d].dimension", i)
+		dimension := strings.ToLower(strings.TrimSpace(subRatings[i].Dimension))
+		switch {
+		case !allowed[dimension]:
+			fields = append(fields, utils.FieldError{Field: field, Message: fmt.Sprintf("must be one of %s", strings.Join(config.RatingDimensions(), ", "))})
+		case seen[dimension]:
+			fields = append(fields, utils.FieldError{Field: field, Message: "is duplicated"})
+		}
+		seen[dimension] = true
+		subRatings[i].Dimension = dimension
+	}
+
+	return fields
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetReviewRevisions godoc
+// @Summary Get the edit history of a review
+// @Description Get every stored version of a review, oldest first, each with a word diff of its content against the version before it
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} []models.RevisionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /reviews/{id}/revisions [get]
+func GetReviewRevisions(c *gin.Context) {
+	review, found := findReview(c)
+	if !found {
+		return
+	}
+
+	var revisions []models.ReviewRevision
+	if err := config.DB.Where("review_id = ?", review.ID).Order("revision ASC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := []models.RevisionResponse{}
+	for i, revision := range revisions {
+		entry := models.RevisionResponse{
+			Revision:   revision.Revision,
+			VariantID:  revision.VariantID,
+			Rating:     revision.Rating,
+			SubRatings: revision.SubRatings,
+			Content:    revision.Content,
+			Pros:       revision.Pros,
+			Cons:       revision.Cons,
+			CreatedAt:  revision.CreatedAt,
+		}
+		if i > 0 {
+			before := revisions[i-1]
+			entry.PreviousRating = &before.Rating
+			entry.Diff = utils.DiffWords(before.Content, revision.Content)
+		}
+		response = append(response, entry)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// reviewChanged reports whether an edit touched any of the reviewed text,
+// the rating, the sub-ratings or the variant. after must carry the
+// sub-ratings the edit saves.
+func reviewChanged(before, after models.Review) bool {
+	return before.Rating != after.Rating ||
+		before.Content != after.Content ||
+		!slices.Equal(before.Pros, after.Pros) ||
+		!slices.Equal(before.Cons, after.Cons) ||
+		!sameVariant(before.VariantID, after.VariantID) ||
+		!maps.Equal(subRatingScores(before.SubRatings), subRatingScores(after.SubRatings))
+}
+
+func sameVariant(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// subRatingScores maps every rated dimension to its score.
+func subRatingScores(subRatings []models.SubRating) map[string]int {
+	scores := make(map[string]int, len(subRatings))
+	for _, subRating := range subRatings {
+		scores[subRating.Dimension] = subRating.Score
+	}
+	return scores
+}
+
+// recordRevisions stores the edited version of a review as its next
+// revision. Reviews written before revisions existed first get their
+// original version stored as revision 1.
+func recordRevisions(tx *gorm.DB, before, after models.Review) error {
+	var latest int
+	if err := tx.Model(&models.ReviewRevision{}).
+		Select("COALESCE(MAX(revision), 0)").
+		Where("review_id = ?", after.ID).
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	if latest == 0 {
+		original := revisionOf(before, 1)
+		original.CreatedAt = before.CreatedAt
+		if before.EditedAt != nil {
+			original.CreatedAt = *before.EditedAt
+		}
+		if err := tx.Create(&original).Error; err != nil {
+			return err
+		}
+		latest = 1
+	}
+
+	revision := revisionOf(after, latest+1)
+	return tx.Create(&revision).Error
+}
+
+func revisionOf(review models.Review, number int) models.ReviewRevision {
+	return models.ReviewRevision{
+		ReviewID:   review.ID,
+		Revision:   number,
+		VariantID:  review.VariantID,
+		Rating:     review.Rating,
+		SubRatings: subRatingScores(review.SubRatings),
+		Content:    review.Content,
+		Pros:       review.Pros,
+		Cons:       review.Cons,
+	}
+}
@@ -0,0 +1,710 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/units"
+	"backend-vercel-phone-review/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSpecTextLength bounds text and enum spec values.
+const maxSpecTextLength = 200
+
+// specFlags are the words a boolean spec value may be written as.
+var specFlags = map[string]bool{
+	"yes": true, "true": true, "supported": true, "y": true,
+	"no": false, "false": false, "unsupported": false, "none": false, "n": false,
+}
+
+var specKeyPattern = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// GetSpecDefinitions godoc
+// @Summary Get the spec registry
+// @Description Get every spec a phone can have, grouped and ordered for display
+// @Tags specs
+// @Produce json
+// @Success 200 {array} models.SpecDefinition
+// @Router /specs [get]
+func GetSpecDefinitions(c *gin.Context) {
+	definitions, err := loadSpecDefinitions(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, definitions)
+}
+
+// CreateSpecDefinition godoc
+// @Summary Add a spec to the registry
+// @Description Add a typed spec phones can have a value for. Admins only.
+// @Tags specs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param spec body models.CreateSpecDefinitionRequest true "Spec definition"
+// @Success 201 {object} models.SpecDefinition
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]string
+// @Router /specs [post]
+func CreateSpecDefinition(c *gin.Context) {
+	var input models.CreateSpecDefinitionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	fields := validateSpecDefinition(input.SpecDefinitionRequest)
+	if !specKeyPattern.MatchString(input.Key) {
+		fields = append(fields, utils.FieldError{Field: "key", Message: "must be lower case letters and digits separated by underscores"})
+	}
+	if len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+
+	var definition models.SpecDefinition
+	err := config.DB.Unscoped().Where(map[string]interface{}{"key": input.Key}).First(&definition).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err == nil && !definition.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "a spec with this key already exists"})
+		return
+	}
+
+	// A removed spec still holds its key, so it is brought back instead
+	definition.Key = input.Key
+	definition.DeletedAt = gorm.DeletedAt{}
+	applySpecDefinition(&definition, input.SpecDefinitionRequest)
+	if err := config.DB.Unscoped().Save(&definition).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, definition)
+}
+
+// UpdateSpecDefinition godoc
+// @Summary Update a spec of the registry
+// @Description Update the label, unit, group, order, type or options of a spec. The type cannot change, nor an option be removed, while phones or variants use them. Changing the unit converts the values phones and variants have, and is refused when the units do not convert into each other. Admins only.
+// @Tags specs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param key path string true "Spec key"
+// @Param spec body models.SpecDefinitionRequest true "Spec definition"
+// @Success 200 {object} models.SpecDefinition
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /specs/{key} [put]
+func UpdateSpecDefinition(c *gin.Context) {
+	var input models.SpecDefinitionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	if fields := validateSpecDefinition(input); len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+
+	var definition models.SpecDefinition
+	if err := config.DB.Where(map[string]interface{}{"key": c.Param("key")}).First(&definition).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "spec not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if input.DataType != definition.DataType {
+		count, err := countSpecUses(config.DB, definition.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "the type of a spec cannot change while phones or variants have a value for it"})
+			return
+		}
+	} else if input.DataType == models.SpecEnum {
+		count, err := countSpecUses(config.DB, definition.ID, "text_value NOT IN ?", input.Options)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "an option cannot be removed while phones or variants use it"})
+			return
+		}
+	}
+
+	previousUnit := definition.Unit
+	applySpecDefinition(&definition, input)
+
+	convert := false
+	if definition.Unit != previousUnit {
+		count, err := countSpecUses(config.DB, definition.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count > 0 {
+			if !unitsConvert(definition.DataType, previousUnit, definition.Unit) {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("the unit of a spec cannot change from %q to %q while phones or variants have a value for it", previousUnit, definition.Unit)})
+				return
+			}
+			convert = true
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if convert {
+			if err := convertSpecValues(tx, definition, previousUnit); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&definition).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// countSpecUses counts the values phones and variants have for a spec,
+// narrowed by an optional condition on the value columns.
+func countSpecUses(db *gorm.DB, definitionID uint, condition string, args ...interface{}) (int64, error) {
+	var total int64
+	for _, model := range []interface{}{&models.SpecValue{}, &models.VariantSpecValue{}} {
+		query := db.Model(model).Where("spec_definition_id = ?", definitionID)
+		if condition != "" {
+			query = query.Where(condition, args...)
+		}
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// unitsConvert reports whether values of a spec of the given type can be
+// converted from one unit to the other.
+func unitsConvert(dataType, from, to string) bool {
+	if dataType != models.SpecNumber && dataType != models.SpecInteger {
+		return false
+	}
+	if _, ok := units.Canonical(from); !ok {
+		return false
+	}
+	_, err := units.Convert(units.Quantity{Value: 1, Unit: from}, to)
+	return err == nil
+}
+
+// convertSpecValues rewrites the phone and variant values of a spec from the
+// unit from to the definition's unit. Integer specs are rounded to whole
+// numbers.
+func convertSpecValues(tx *gorm.DB, definition models.SpecDefinition, from string) error {
+	var values []models.SpecValue
+	if err := tx.Where("spec_definition_id = ? AND number_value IS NOT NULL", definition.ID).Find(&values).Error; err != nil {
+		return err
+	}
+	for _, value := range values {
+		number, err := convertSpecNumber(definition, *value.Number, from)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&value).Update("number_value", number).Error; err != nil {
+			return err
+		}
+	}
+
+	var overrides []models.VariantSpecValue
+	if err := tx.Where("spec_definition_id = ? AND number_value IS NOT NULL", definition.ID).Find(&overrides).Error; err != nil {
+		return err
+	}
+	for _, override := range overrides {
+		number, err := convertSpecNumber(definition, *override.Number, from)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&override).Update("number_value", number).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertSpecNumber(definition models.SpecDefinition, number float64, from string) (float64, error) {
+	converted, err := units.Convert(units.Quantity{Value: number, Unit: from}, definition.Unit)
+	if err != nil {
+		return 0, err
+	}
+	if definition.DataType == models.SpecInteger {
+		converted = math.Round(converted)
+	}
+	return converted, nil
+}
+
+// DeleteSpecDefinition godoc
+// @Summary Remove a spec from the registry
+// @Description Remove a spec and every phone's and variant's value for it. Admins only.
+// @Tags specs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param key path string true "Spec key"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /specs/{key} [delete]
+func DeleteSpecDefinition(c *gin.Context) {
+	var definition models.SpecDefinition
+	if err := config.DB.Where(map[string]interface{}{"key": c.Param("key")}).First(&definition).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "spec not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("spec_definition_id = ?", definition.ID).Delete(&models.SpecValue{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("spec_definition_id = ?", definition.ID).Delete(&models.VariantSpecValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&definition).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "spec deleted successfully"})
+}
+
+// UpdatePhoneSpecs godoc
+// @Summary Set the specs of a phone
+// @Description Set spec values of a phone by spec key, such as {"battery_capacity": 5000, "os": "Android"}. Each value must match the type of its spec in the registry; quantities may also be given as text with a unit, such as "6.7 in", and are converted to the spec's unit. null removes a value and specs left out keep theirs.
+// @Tags specs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param phone_id path int true "Phone ID"
+// @Param specs body map[string]interface{} true "Spec values by key"
+// @Success 200 {array} models.SpecGroup
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /phones/{phone_id}/specs [put]
+func UpdatePhoneSpecs(c *gin.Context) {
+	var input map[string]interface{}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	values, removed, fields, err := readSpecValues(config.DB, input, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+	for i := range values {
+		values[i].PhoneID = phone.ID
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(removed) > 0 {
+			if err := tx.Unscoped().Where("phone_id = ? AND spec_definition_id IN ?", phone.ID, removed).Delete(&models.SpecValue{}).Error; err != nil {
+				return err
+			}
+		}
+		return saveSpecValues(tx, values)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	specs, err := loadPhoneSpecs(config.DB, phone.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, specs)
+}
+
+// readSpecValues checks spec values given by key against the registry.
+// It returns the values to store, the IDs of the specs set to null and the
+// field errors, whose names start with prefix.
+func readSpecValues(db *gorm.DB, input map[string]interface{}, prefix string) ([]models.SpecValue, []uint, []utils.FieldError, error) {
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var definitions []models.SpecDefinition
+	if len(keys) > 0 {
+		if err := db.Where(map[string]interface{}{"key": keys}).Find(&definitions).Error; err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	byKey := make(map[string]models.SpecDefinition, len(definitions))
+	for _, definition := range definitions {
+		byKey[definition.Key] = definition
+	}
+
+	var fields []utils.FieldError
+	var values []models.SpecValue
+	var removed []uint
+	for _, key := range keys {
+		definition, ok := byKey[key]
+		if !ok {
+			fields = append(fields, utils.FieldError{Field: prefix + key, Message: "is not a known spec"})
+			continue
+		}
+		if input[key] == nil {
+			removed = append(removed, definition.ID)
+			continue
+		}
+
+		value, message := specValueFor(definition, input[key])
+		if message != "" {
+			fields = append(fields, utils.FieldError{Field: prefix + key, Message: message})
+			continue
+		}
+		values = append(values, value)
+	}
+	return values, removed, fields, nil
+}
+
+// saveSpecValues inserts spec values, replacing a phone's earlier value of
+// the same spec along with the feature it came from.
+func saveSpecValues(tx *gorm.DB, values []models.SpecValue) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "phone_id"}, {Name: "spec_definition_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"feature_id", "number_value", "text_value", "bool_value", "updated_at", "deleted_at"}),
+	}).Create(&values).Error
+}
+
+// validateSpecDefinition checks what the binding tags cannot: only enums
+// have options, and they need at least one; text cannot be ranked.
+func validateSpecDefinition(input models.SpecDefinitionRequest) []utils.FieldError {
+	if input.DataType == models.SpecEnum && len(input.Options) == 0 {
+		return []utils.FieldError{{Field: "options", Message: "is required for an enum"}}
+	}
+	if input.DataType != models.SpecEnum && len(input.Options) > 0 {
+		return []utils.FieldError{{Field: "options", Message: "is only allowed for an enum"}}
+	}
+	if input.Better != "" && (input.DataType == models.SpecText || input.DataType == models.SpecEnum) {
+		return []utils.FieldError{{Field: "better", Message: "is only allowed for numbers and booleans"}}
+	}
+	return nil
+}
+
+func applySpecDefinition(definition *models.SpecDefinition, input models.SpecDefinitionRequest) {
+	definition.Label = input.Label
+	definition.DataType = input.DataType
+	definition.Unit = input.Unit
+	if unit, ok := units.Canonical(input.Unit); ok {
+		definition.Unit = unit
+	}
+	definition.Group = input.Group
+	definition.Options = input.Options
+	definition.Better = input.Better
+	definition.Position = input.Position
+}
+
+// specValueFor converts a decoded JSON value to the column of the spec's
+// data type, or explains why it does not fit. Strings are parsed, so
+// "5,000 mAh" is as good as 5000 for a battery capacity.
+func specValueFor(definition models.SpecDefinition, raw interface{}) (models.SpecValue, string) {
+	if text, ok := raw.(string); ok {
+		return parseSpecText(definition, text)
+	}
+
+	value := models.SpecValue{SpecDefinitionID: definition.ID}
+	switch definition.DataType {
+	case models.SpecNumber, models.SpecInteger:
+		number, ok := raw.(float64)
+		if !ok {
+			return value, "must be a number"
+		}
+		return numberSpecValue(definition, number)
+	case models.SpecBoolean:
+		flag, ok := raw.(bool)
+		if !ok {
+			return value, "must be true or false"
+		}
+		value.Bool = &flag
+	default:
+		return value, "must be a non-empty string"
+	}
+	return value, ""
+}
+
+// parseSpecText reads a spec value typed as text, converting quantities to
+// the spec's unit and matching enum options regardless of case.
+func parseSpecText(definition models.SpecDefinition, text string) (models.SpecValue, string) {
+	value := models.SpecValue{SpecDefinitionID: definition.ID}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return value, "must be a non-empty string"
+	}
+
+	switch definition.DataType {
+	case models.SpecNumber, models.SpecInteger:
+		number, err := units.ParseAs(text, definition.Unit)
+		if err != nil {
+			return value, err.Error()
+		}
+		return numberSpecValue(definition, number)
+	case models.SpecBoolean:
+		flag, ok := specFlags[strings.ToLower(text)]
+		if !ok {
+			return value, "must be yes or no"
+		}
+		value.Bool = &flag
+		return value, ""
+	}
+
+	if len([]rune(text)) > maxSpecTextLength {
+		return value, fmt.Sprintf("must be at most %d characters long", maxSpecTextLength)
+	}
+	if utils.ContainsHTML(text) {
+		return value, "must not contain HTML"
+	}
+	if definition.DataType == models.SpecEnum {
+		i := slices.IndexFunc(definition.Options, func(option string) bool { return strings.EqualFold(option, text) })
+		if i < 0 {
+			return value, "must be one of " + strings.Join(definition.Options, ", ")
+		}
+		text = definition.Options[i]
+	}
+	value.Text = &text
+	return value, ""
+}
+
+func numberSpecValue(definition models.SpecDefinition, number float64) (models.SpecValue, string) {
+	value := models.SpecValue{SpecDefinitionID: definition.ID}
+	if definition.DataType == models.SpecInteger && number != math.Trunc(number) {
+		return value, "must be a whole number"
+	}
+	value.Number = &number
+	return value, ""
+}
+
+// specValue is the JSON value of a stored spec value.
+func specValue(definition models.SpecDefinition, value models.SpecValue) interface{} {
+	switch {
+	case value.Number != nil && definition.DataType == models.SpecInteger:
+		return int64(*value.Number)
+	case value.Number != nil:
+		return *value.Number
+	case value.Bool != nil:
+		return *value.Bool
+	case value.Text != nil:
+		return *value.Text
+	}
+	return nil
+}
+
+// loadSpecDefinitions returns the registry in display order: by group, then
+// by position within the group.
+func loadSpecDefinitions(db *gorm.DB) ([]models.SpecDefinition, error) {
+	var definitions []models.SpecDefinition
+	if err := db.Order("position ASC").Order("id ASC").Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(definitions, func(i, j int) bool {
+		return specGroupIndex(definitions[i].Group) < specGroupIndex(definitions[j].Group)
+	})
+	return definitions, nil
+}
+
+func specGroupIndex(group string) int {
+	if i := slices.Index(models.SpecGroups, group); i >= 0 {
+		return i
+	}
+	return len(models.SpecGroups)
+}
+
+// loadPhoneSpecs returns the spec values of a phone grouped for display.
+// Groups without values are left out.
+func loadPhoneSpecs(db *gorm.DB, phoneID uint) ([]models.SpecGroup, error) {
+	definitions, err := loadSpecDefinitions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []models.SpecValue
+	if err := db.Where("phone_id = ?", phoneID).Find(&values).Error; err != nil {
+		return nil, err
+	}
+	byDefinition := make(map[uint]models.SpecValue, len(values))
+	for _, value := range values {
+		byDefinition[value.SpecDefinitionID] = value
+	}
+
+	return groupSpecs(definitions, byDefinition), nil
+}
+
+// groupSpecs lays out spec values, keyed by spec definition ID, in the
+// display order of definitions.
+func groupSpecs(definitions []models.SpecDefinition, byDefinition map[uint]models.SpecValue) []models.SpecGroup {
+	groups := []models.SpecGroup{}
+	for _, definition := range definitions {
+		value, ok := byDefinition[definition.ID]
+		if !ok {
+			continue
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Group != definition.Group {
+			groups = append(groups, models.SpecGroup{Group: definition.Group})
+		}
+		last := &groups[len(groups)-1]
+		last.Specs = append(last.Specs, models.Spec{
+			Key:      definition.Key,
+			Label:    definition.Label,
+			DataType: definition.DataType,
+			Unit:     definition.Unit,
+			Value:    specValue(definition, value),
+		})
+	}
+	return groups
+}
+
+// filterPhonesBySpecs narrows a phone query to the min[key] and max[key]
+// bounds of numeric specs and sorts it by sort_spec. It writes the error
+// response itself when a parameter is invalid.
+func filterPhonesBySpecs(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	bounds := map[string]map[string]string{">=": c.QueryMap("min"), "<=": c.QueryMap("max")}
+	sortKey := c.Query("sort_spec")
+	if len(bounds[">="]) == 0 && len(bounds["<="]) == 0 && sortKey == "" {
+		return query, true
+	}
+
+	definitions, err := loadSpecDefinitions(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	numeric := make(map[string]models.SpecDefinition)
+	for _, definition := range definitions {
+		if definition.DataType == models.SpecNumber || definition.DataType == models.SpecInteger {
+			numeric[definition.Key] = definition
+		}
+	}
+
+	var fields []utils.FieldError
+	joins := 0
+	for _, op := range []string{">=", "<="} {
+		param := "min"
+		if op == "<=" {
+			param = "max"
+		}
+
+		keys := make([]string, 0, len(bounds[op]))
+		for key := range bounds[op] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			field := fmt.Sprintf("%s[%s]", param, key)
+			definition, ok := numeric[key]
+			if !ok {
+				fields = append(fields, utils.FieldError{Field: field, Message: "is not a numeric spec"})
+				continue
+			}
+			bound, err := units.ParseAs(bounds[op][key], definition.Unit)
+			if err != nil {
+				fields = append(fields, utils.FieldError{Field: field, Message: err.Error()})
+				continue
+			}
+
+			alias := fmt.Sprintf("spec_filter_%d", joins)
+			joins++
+			query = query.
+				Joins(fmt.Sprintf("JOIN spec_values AS %[1]s ON %[1]s.phone_id = phones.id AND %[1]s.spec_definition_id = ? AND %[1]s.deleted_at IS NULL", alias), definition.ID).
+				Where(fmt.Sprintf("%s.number_value %s ?", alias, op), bound)
+		}
+	}
+
+	if sortKey != "" {
+		definition, ok := numeric[sortKey]
+		if !ok {
+			fields = append(fields, utils.FieldError{Field: "sort_spec", Message: "is not a numeric spec"})
+		}
+		direction := "DESC"
+		switch c.Query("order") {
+		case "", "desc":
+		case "asc":
+			direction = "ASC"
+		default:
+			fields = append(fields, utils.FieldError{Field: "order", Message: "must be one of asc, desc"})
+		}
+		if ok {
+			query = query.
+				Joins("LEFT JOIN spec_values AS spec_sort ON spec_sort.phone_id = phones.id AND spec_sort.spec_definition_id = ? AND spec_sort.deleted_at IS NULL", definition.ID).
+				Order("spec_sort.number_value IS NULL").
+				Order("spec_sort.number_value " + direction).
+				Order("phones.id ASC")
+		}
+	}
+
+	if len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return nil, false
+	}
+	return query.Select("phones.*"), true
+}
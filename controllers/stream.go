@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/events"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/outbox"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment line so
+// proxies do not close it.
+const streamHeartbeat = 25 * time.Second
+
+// StreamPhoneReviews godoc
+// @Summary Stream new reviews of a phone
+// @Description Server-Sent Events stream with a "review" event for every review published for the phone
+// @Tags phones
+// @Produce text/event-stream
+// @Param phone_id path int true "Phone ID"
+// @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /phones/{phone_id}/reviews/stream [get]
+func StreamPhoneReviews(c *gin.Context) {
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	streamEvents(c, events.PhoneReviewsTopic(phone.ID))
+}
+
+// StreamReviewComments godoc
+// @Summary Stream new comments on a review
+// @Description Server-Sent Events stream with a "comment" event for every comment published on the review
+// @Tags comments
+// @Produce text/event-stream
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /reviews/{id}/comments/stream [get]
+func StreamReviewComments(c *gin.Context) {
+	review, found := findReview(c)
+	if !found {
+		return
+	}
+
+	streamEvents(c, events.ReviewCommentsTopic(review.ID))
+}
+
+// StreamNotifications godoc
+// @Summary Stream my notifications
+// @Description Server-Sent Events stream with a "notification" event for every new notification of the authenticated user. Browsers that cannot set headers on EventSource may pass a ticket from POST /notifications/stream/ticket instead.
+// @Tags notifications
+// @Produce text/event-stream
+// @Param Authorization header string false "JWT Authorization header"
+// @Param ticket query string false "Stream ticket, when the header cannot be set"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Notification
+// @Failure 401 {object} map[string]string
+// @Router /notifications/stream [get]
+func StreamNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	streamEvents(c, events.UserNotificationsTopic(userID))
+}
+
+// streamTicketLifetime is how long a stream ticket can open the
+// notification stream.
+const streamTicketLifetime = time.Minute
+
+// CreateStreamTicket godoc
+// @Summary Get a ticket for the notification stream
+// @Description Get a ticket that opens the notification stream of the authenticated user as the ticket query parameter. It expires after a minute, so clients fetch a new one for every connection.
+// @Tags notifications
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.StreamTicket
+// @Router /notifications/stream/ticket [post]
+func CreateStreamTicket(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	expiresAt := time.Now().Add(streamTicketLifetime)
+	claims := &Claims{
+		UserID: userID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  models.StreamTicketAudience,
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate ticket"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.StreamTicket{Ticket: ticket, ExpiresAt: expiresAt})
+}
+
+// streamEvents relays a topic's events to the client until it disconnects.
+func streamEvents(c *gin.Context, topic string) {
+	updates, unsubscribe := config.Events.Subscribe(topic)
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-updates:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Name, event.Data)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// publish sends an update to the streams of a topic straight from the
+// request, and must only be called once the change has been committed.
+// Failures are only logged: the change itself has already been saved.
+//
+// Review and comment announcements go out this way rather than through the
+// outbox: they carry no state of their own, clients that miss one catch up
+// on their next fetch, and queueing them would hold a live feed back until
+// the next worker run. Anything published from inside a transaction goes
+// through queueEvent instead.
+func publish(topic, name string, data interface{}) {
+	event, err := events.NewEvent(topic, name, data)
+	if err != nil {
+		log.Printf("Could not publish %s event on %s: %v", name, topic, err)
+		return
+	}
+	publishEvent(event)
+}
+
+func publishEvent(event events.Event) {
+	if err := config.Events.Publish(event); err != nil {
+		log.Printf("Could not publish %s event on %s: %v", event.Name, event.Topic, err)
+	}
+}
+
+// pendingEventsKey holds, in the context of a transaction run by
+// withEvents, the events queued inside it.
+type pendingEventsKey struct{}
+
+// withEvents runs fn in a transaction the way config.DB.Transaction does,
+// and publishes the events queueEvent was given inside it from the request
+// once it has committed. The outbox worker may run in another process than
+// the streams, where the in-process broker would never reach them.
+func withEvents(fn func(tx *gorm.DB) error) error {
+	var pending []events.Event
+	ctx := context.WithValue(context.Background(), pendingEventsKey{}, &pending)
+	if err := config.DB.WithContext(ctx).Transaction(fn); err != nil {
+		return err
+	}
+
+	for _, event := range pending {
+		publishEvent(event)
+	}
+	return nil
+}
+
+// queueEvent publishes an update from inside the transaction of the change,
+// so streams never see a change that is rolled back or not visible yet.
+// Inside withEvents the update goes out once the transaction commits;
+// anywhere else, such as in background jobs, through the outbox.
+func queueEvent(tx *gorm.DB, topic, name string, data interface{}) error {
+	event, err := events.NewEvent(topic, name, data)
+	if err != nil {
+		return err
+	}
+
+	if pending, ok := tx.Statement.Context.Value(pendingEventsKey{}).(*[]events.Event); ok {
+		*pending = append(*pending, event)
+		return nil
+	}
+	return outbox.Enqueue(tx, jobEventPublish, event, outbox.Options{})
+}
+
+// publishQueuedEvent publishes an event queued by queueEvent.
+func publishQueuedEvent(ctx context.Context, tx *gorm.DB, raw json.RawMessage) error {
+	var event events.Event
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	if err := config.Events.Publish(event); err != nil {
+		if errors.Is(err, events.ErrEventTooLarge) {
+			return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// announceReview tells the phone's stream about a review that has just
+// become visible. previous is nil for new reviews.
+func announceReview(review models.Review, previous *models.Review) {
+	if !becamePublished(review, previous) {
+		return
+	}
+	publish(events.PhoneReviewsTopic(review.PhoneID), "review", review)
+}
+
+// publishComment announces a published comment to its review's stream.
+func publishComment(comment models.Comment) {
+	if comment.Status != models.CommentStatusPublished {
+		return
+	}
+	publish(events.ReviewCommentsTopic(comment.ReviewID), "comment", comment)
+}
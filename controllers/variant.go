@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetVariants godoc
+// @Summary Get the variants of a phone
+// @Description Get the variants of a phone, each with the phone's specs overridden by its own
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Param phone_id path int true "Phone ID"
+// @Success 200 {array} models.Variant
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /phones/{phone_id}/variants [get]
+func GetVariants(c *gin.Context) {
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	variants, err := loadVariants(config.DB, phone.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// CreateVariant godoc
+// @Summary Add a variant to a phone
+// @Description Add a storage, memory or color variant to a phone, with its identifiers, price and the spec values in which it differs from the phone
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param phone_id path int true "Phone ID"
+// @Param variant body models.VariantRequest true "Variant"
+// @Success 201 {object} models.Variant
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /phones/{phone_id}/variants [post]
+func CreateVariant(c *gin.Context) {
+	var input models.VariantRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return
+	}
+
+	var phone models.Phone
+	if err := config.DB.First(&phone, phoneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "phone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	variant := models.Variant{PhoneID: phone.ID}
+	saveVariant(c, &variant, input)
+}
+
+// UpdateVariant godoc
+// @Summary Update a variant of a phone
+// @Description Update a variant of a phone. Specs, when given, replace all of the variant's own spec values.
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param phone_id path int true "Phone ID"
+// @Param variant_id path int true "Variant ID"
+// @Param variant body models.VariantRequest true "Variant"
+// @Success 200 {object} models.Variant
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /phones/{phone_id}/variants/{variant_id} [put]
+func UpdateVariant(c *gin.Context) {
+	var input models.VariantRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	variant, ok := findVariant(c)
+	if !ok {
+		return
+	}
+
+	saveVariant(c, &variant, input)
+}
+
+// DeleteVariant godoc
+// @Summary Delete a variant of a phone
+// @Description Delete a variant of a phone. Its reviews stay with the phone.
+// @Tags variants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param phone_id path int true "Phone ID"
+// @Param variant_id path int true "Variant ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /phones/{phone_id}/variants/{variant_id} [delete]
+func DeleteVariant(c *gin.Context) {
+	variant, ok := findVariant(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Review{}).Where("variant_id = ?", variant.ID).Update("variant_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("variant_id = ?", variant.ID).Delete(&models.VariantSpecValue{}).Error; err != nil {
+			return err
+		}
+		// Deleted for good so its EAN can be given to another variant
+		return tx.Unscoped().Delete(&variant).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "variant deleted successfully"})
+}
+
+// findVariant loads the variant of the variant_id path parameter, which
+// must belong to the phone of phone_id. It writes the error response itself.
+func findVariant(c *gin.Context) (models.Variant, bool) {
+	phoneID, ok := pathID(c, "phone_id", "phone")
+	if !ok {
+		return models.Variant{}, false
+	}
+	variantID, ok := pathID(c, "variant_id", "variant")
+	if !ok {
+		return models.Variant{}, false
+	}
+
+	var variant models.Variant
+	if err := config.DB.Where("phone_id = ?", phoneID).First(&variant, variantID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return variant, false
+	}
+	return variant, true
+}
+
+// saveVariant applies a request to a new or existing variant and answers
+// with the variant and its resulting specs.
+func saveVariant(c *gin.Context, variant *models.Variant, input models.VariantRequest) {
+	values, _, fields, err := readSpecValues(config.DB, input.Specs, "specs.")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fields) > 0 {
+		utils.RespondValidationError(c, fields)
+		return
+	}
+
+	status := http.StatusOK
+	if variant.ID == 0 {
+		status = http.StatusCreated
+	}
+
+	variant.Name = input.Name
+	variant.ModelNumber = input.ModelNumber
+	variant.EAN = input.EAN
+	if variant.EAN != nil && *variant.EAN == "" {
+		variant.EAN = nil
+	}
+	variant.Color = input.Color
+	variant.Price = input.Price
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(variant).Error; err != nil {
+			return err
+		}
+		if input.Specs == nil {
+			return nil
+		}
+
+		if err := tx.Unscoped().Where("variant_id = ?", variant.ID).Delete(&models.VariantSpecValue{}).Error; err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		overrides := make([]models.VariantSpecValue, len(values))
+		for i, value := range values {
+			overrides[i] = models.VariantSpecValue{
+				VariantID:        variant.ID,
+				SpecDefinitionID: value.SpecDefinitionID,
+				Number:           value.Number,
+				Text:             value.Text,
+				Bool:             value.Bool,
+			}
+		}
+		return tx.Create(&overrides).Error
+	})
+	if err != nil {
+		if err == gorm.ErrDuplicatedKey {
+			c.JSON(http.StatusConflict, gin.H{"error": "a variant with this EAN already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	variants, err := loadVariants(config.DB, variant.PhoneID, variant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(variants) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+
+	c.JSON(status, variants[0])
+}
+
+// loadVariants returns the variants of a phone, or only those with the
+// given IDs, with the phone's spec values overridden by each variant's own.
+func loadVariants(db *gorm.DB, phoneID uint, ids ...uint) ([]models.Variant, error) {
+	query := db.Where("phone_id = ?", phoneID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	variants := []models.Variant{}
+	if err := query.Order("id ASC").Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	if len(variants) == 0 {
+		return variants, nil
+	}
+
+	definitions, err := loadSpecDefinitions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var phoneValues []models.SpecValue
+	if err := db.Where("phone_id = ?", phoneID).Find(&phoneValues).Error; err != nil {
+		return nil, err
+	}
+
+	variantIDs := make([]uint, len(variants))
+	for i, variant := range variants {
+		variantIDs[i] = variant.ID
+	}
+	var overrides []models.VariantSpecValue
+	if err := db.Where("variant_id IN ?", variantIDs).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range variants {
+		byDefinition := make(map[uint]models.SpecValue, len(phoneValues))
+		for _, value := range phoneValues {
+			byDefinition[value.SpecDefinitionID] = value
+		}
+		for _, override := range overrides {
+			if override.VariantID != variants[i].ID {
+				continue
+			}
+			byDefinition[override.SpecDefinitionID] = models.SpecValue{
+				PhoneID:          phoneID,
+				SpecDefinitionID: override.SpecDefinitionID,
+				Number:           override.Number,
+				Text:             override.Text,
+				Bool:             override.Bool,
+			}
+		}
+		variants[i].Specs = groupSpecs(definitions, byDefinition)
+	}
+	return variants, nil
+}
+
+// validateReviewVariant checks that the variant a review names, if any, is
+// a variant of the reviewed phone.
+func validateReviewVariant(phoneID uint, variantID *uint) ([]utils.FieldError, error) {
+	if variantID == nil {
+		return nil, nil
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Variant{}).Where("id = ? AND phone_id = ?", *variantID, phoneID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return []utils.FieldError{{Field: "variant_id", Message: "is not a variant of this phone"}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VoteReview godoc
+// @Summary Vote on a review
+// @Description Mark a review as helpful or not helpful. Voting again changes the vote; authors cannot vote on their own review.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Review ID"
+// @Param vote body models.VoteRequest true "Vote"
+// @Success 200 {object} models.VoteSummary
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /reviews/{id}/vote [put]
+func VoteReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var input models.VoteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	review, found := findReview(c)
+	if !found {
+		return
+	}
+
+	if review.UserID == userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you cannot vote on your own review"})
+		return
+	}
+
+	vote := models.ReviewVote{ReviewID: review.ID, UserID: userID, Helpful: *input.Helpful}
+	err := withEvents(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.ReviewVote{}).Where("review_id = ? AND user_id = ?", review.ID, userID).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "review_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"helpful", "updated_at"}),
+		}).Create(&vote).Error; err != nil {
+			return err
+		}
+		if err := recountVotes(tx, review.ID); err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+		return notifyVote(tx, review, vote)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondVoteSummary(c, review.ID, input.Helpful)
+}
+
+// DeleteVote godoc
+// @Summary Remove my vote on a review
+// @Description Remove the authenticated user's helpful / not helpful vote on a review
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.VoteSummary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /reviews/{id}/vote [delete]
+func DeleteVote(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	review, found := findReview(c)
+	if !found {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("review_id = ? AND user_id = ?", review.ID, userID).Delete(&models.ReviewVote{}).Error; err != nil {
+			return err
+		}
+		return recountVotes(tx, review.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondVoteSummary(c, review.ID, nil)
+}
+
+// findReview loads the published review named by the id path parameter,
+// writing the error response itself when it cannot.
+func findReview(c *gin.Context) (models.Review, bool) {
+	reviewID, ok := pathID(c, "id", "review")
+	if !ok {
+		return models.Review{}, false
+	}
+
+	var review models.Review
+	if err := config.DB.Scopes(publishedReviews).First(&review, reviewID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return review, false
+	}
+	return review, true
+}
+
+// recountVotes refreshes the denormalised vote counters on a review.
+func recountVotes(tx *gorm.DB, reviewID uint) error {
+	return tx.Exec(`UPDATE reviews SET
+		helpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = ? AND helpful = ? AND deleted_at IS NULL),
+		not_helpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = ? AND helpful = ? AND deleted_at IS NULL)
+		WHERE id = ?`, reviewID, true, reviewID, false, reviewID).Error
+}
+
+func respondVoteSummary(c *gin.Context, reviewID uint, myVote *bool) {
+	var review models.Review
+	if err := config.DB.First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.VoteSummary{
+		ReviewID:        review.ID,
+		HelpfulCount:    review.HelpfulCount,
+		NotHelpfulCount: review.NotHelpfulCount,
+		MyVote:          myVote,
+	})
+}
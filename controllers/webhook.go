@@ -0,0 +1,562 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/outbox"
+	"backend-vercel-phone-review/utils"
+	"backend-vercel-phone-review/webhooks"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	webhookClient = &http.Client{}
+	// webhookNow is the clock delivery attempts are timed by
+	webhookNow = time.Now
+)
+
+// CreateWebhook godoc
+// @Summary Register a webhook endpoint
+// @Description Register an endpoint that receives signed POSTs for the chosen events. The signing secret is only returned here. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param webhook body models.WebhookRequest true "Webhook"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Router /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var input models.WebhookRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook := models.Webhook{
+		URL:         input.URL,
+		Description: input.Description,
+		Events:      uniqueEvents(input.Events),
+		Secret:      secret,
+		Active:      input.Active == nil || *input.Active,
+		CreatedBy:   userID,
+	}
+	if err := config.DB.Create(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhooks godoc
+// @Summary Get webhook endpoints
+// @Description Get every registered webhook endpoint. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Success 200 {object} []models.Webhook
+// @Failure 403 {object} map[string]string
+// @Router /webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	var hooks []models.Webhook
+	if err := config.DB.Order("id ASC").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook endpoint
+// @Description Get a registered webhook endpoint. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id} [get]
+func GetWebhook(c *gin.Context) {
+	webhook, found := findWebhook(c)
+	if !found {
+		return
+	}
+
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook endpoint
+// @Description Change the URL, description, events or active flag of a webhook endpoint. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Param webhook body models.WebhookRequest true "Webhook"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id} [put]
+func UpdateWebhook(c *gin.Context) {
+	webhook, found := findWebhook(c)
+	if !found {
+		return
+	}
+
+	var input models.WebhookRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	webhook.URL = input.URL
+	webhook.Description = input.Description
+	webhook.Events = uniqueEvents(input.Events)
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	if err := config.DB.Save(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook endpoint
+// @Description Stop sending events to an endpoint and remove it. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	webhook, found := findWebhook(c)
+	if !found {
+		return
+	}
+
+	if err := config.DB.Delete(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries godoc
+// @Summary Get the delivery log of a webhook endpoint
+// @Description Get the deliveries sent to an endpoint, newest first, with the outcome of their latest attempt. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Only deliveries in this status: pending, retrying, succeeded or failed"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} models.WebhookDeliveryPage
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	webhook, found := findWebhook(c)
+	if !found {
+		return
+	}
+
+	p, ok := parsePage(c, sortNewest)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("webhook_deliveries.webhook_id = ?", webhook.ID)
+	switch status := c.Query("status"); status {
+	case "":
+	case models.WebhookDeliveryPending, models.WebhookDeliveryRetrying, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+		query = query.Where("webhook_deliveries.status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery status"})
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := keyset(query, "webhook_deliveries", p).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.WebhookDeliveryPage{Items: deliveries}
+	if len(deliveries) > p.Limit {
+		response.Items = deliveries[:p.Limit]
+		last := response.Items[p.Limit-1]
+		response.NextCursor = utils.EncodeCursor(utils.Cursor{ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+	if response.Items == nil {
+		response.Items = []models.WebhookDelivery{}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingWebhook godoc
+// @Summary Send a test event to a webhook endpoint
+// @Description Send a signed ping event to the endpoint once, without retries, and return the logged delivery. Useful to check a receiver. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id}/ping [post]
+func PingWebhook(c *gin.Context) {
+	webhook, found := findWebhook(c)
+	if !found {
+		return
+	}
+
+	payload, body, err := encodeWebhookEvent(models.WebhookEventPing, gin.H{"webhook_id": webhook.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   payload.ID,
+		Event:     payload.Event,
+		Payload:   body,
+		Status:    models.WebhookDeliveryPending,
+	}
+	if err := config.DB.Create(&delivery).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := config.WebhookRetries()
+	policy.MaxAttempts = 1
+	attemptWebhook(c.Request.Context(), &delivery, webhook, policy)
+	if err := saveWebhookDelivery(config.DB, &delivery); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhook godoc
+// @Summary Redeliver a webhook event
+// @Description Queue the payload of an earlier delivery again as a new delivery, with fresh retries. The event ID is kept so receivers can spot duplicates. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "JWT Authorization header"
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func RedeliverWebhook(c *gin.Context) {
+	webhook, found := findWebhook(c)
+	if !found {
+		return
+	}
+
+	deliveryID, ok := pathID(c, "delivery_id", "delivery")
+	if !ok {
+		return
+	}
+
+	var original models.WebhookDelivery
+	if err := config.DB.Where("webhook_id = ?", webhook.ID).First(&original, deliveryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:    webhook.ID,
+		EventID:      original.EventID,
+		Event:        original.Event,
+		Payload:      original.Payload,
+		Status:       models.WebhookDeliveryPending,
+		RedeliveryOf: &original.ID,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+		return queueWebhookAttempt(tx, delivery)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func findWebhook(c *gin.Context) (models.Webhook, bool) {
+	webhookID, ok := pathID(c, "id", "webhook")
+	if !ok {
+		return models.Webhook{}, false
+	}
+
+	var webhook models.Webhook
+	if err := config.DB.First(&webhook, webhookID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return webhook, false
+	}
+	return webhook, true
+}
+
+func uniqueEvents(events []string) []string {
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(unique, event) {
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
+
+// webhookFanoutJob is the outbox payload of an event waiting to be
+// delivered to the endpoints subscribed to it.
+type webhookFanoutJob struct {
+	Event   string `json:"event"`
+	EventID string `json:"event_id"`
+	Body    string `json:"body"`
+}
+
+// webhookDeliverJob is the outbox payload of a single delivery attempt.
+type webhookDeliverJob struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// dispatchWebhooks queues an event for the endpoints subscribed to it
+// through tx, the transaction of the change the event describes.
+func dispatchWebhooks(tx *gorm.DB, event string, data interface{}) error {
+	payload, body, err := encodeWebhookEvent(event, data)
+	if err != nil {
+		return err
+	}
+
+	return outbox.Enqueue(tx, jobWebhookFanout, webhookFanoutJob{Event: event, EventID: payload.ID, Body: body}, outbox.Options{
+		Key: "webhooks.fanout:" + payload.ID,
+	})
+}
+
+// queueReviewWebhooks queues review.published for a review that has just
+// become visible, review.unpublished for one that was held back or hidden
+// again and review.updated for an edit to a visible one. previous is nil
+// for new reviews.
+func queueReviewWebhooks(tx *gorm.DB, review models.Review, previous *models.Review) error {
+	if becamePublished(review, previous) {
+		return dispatchWebhooks(tx, models.WebhookEventReviewPublished, review)
+	}
+	if previous != nil && previous.Status == models.ReviewStatusPublished && review.Status != models.ReviewStatusPublished {
+		return dispatchWebhooks(tx, models.WebhookEventReviewUnpublished, review)
+	}
+	if review.Status == models.ReviewStatusPublished && reviewChanged(*previous, review) {
+		return dispatchWebhooks(tx, models.WebhookEventReviewUpdated, review)
+	}
+	return nil
+}
+
+// fanOutWebhook logs a delivery of an event for every active endpoint
+// subscribed to it and queues its first attempt.
+func fanOutWebhook(ctx context.Context, tx *gorm.DB, raw json.RawMessage) error {
+	var job webhookFanoutJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	var hooks []models.Webhook
+	if err := tx.Where("active = ?", true).Find(&hooks).Error; err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		if !slices.Contains(hook.Events, job.Event) {
+			continue
+		}
+
+		delivery := models.WebhookDelivery{
+			WebhookID: hook.ID,
+			EventID:   job.EventID,
+			Event:     job.Event,
+			Payload:   job.Body,
+			Status:    models.WebhookDeliveryPending,
+		}
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+		if err := queueWebhookAttempt(tx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueWebhookAttempt schedules the next attempt of a delivery, at its
+// NextAttemptAt or straight away.
+func queueWebhookAttempt(tx *gorm.DB, delivery models.WebhookDelivery) error {
+	opts := outbox.Options{Key: fmt.Sprintf("webhooks.deliver:%d:%d", delivery.ID, delivery.Attempts+1)}
+	if delivery.NextAttemptAt != nil {
+		opts.RunAt = *delivery.NextAttemptAt
+	}
+	return outbox.Enqueue(tx, jobWebhookDeliver, webhookDeliverJob{DeliveryID: delivery.ID}, opts)
+}
+
+// deliverWebhook makes one attempt at a delivery and queues the next one
+// when it failed and attempts remain. It runs outside a transaction, so no
+// transaction is held open while the receiver answers; the outcome and the
+// next attempt are saved together afterwards.
+func deliverWebhook(ctx context.Context, db *gorm.DB, raw json.RawMessage) error {
+	var job webhookDeliverJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery, job.DeliveryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	if delivery.Status == models.WebhookDeliverySucceeded || delivery.Status == models.WebhookDeliveryFailed {
+		return nil
+	}
+
+	var webhook models.Webhook
+	if err := db.First(&webhook, delivery.WebhookID).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = "endpoint was deleted"
+		delivery.NextAttemptAt = nil
+		return saveWebhookDelivery(db, &delivery)
+	}
+
+	attemptWebhook(ctx, &delivery, webhook, config.WebhookRetries())
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := saveWebhookDelivery(tx, &delivery); err != nil {
+			return err
+		}
+		if delivery.NextAttemptAt == nil {
+			return nil
+		}
+		return queueWebhookAttempt(tx, delivery)
+	})
+}
+
+func encodeWebhookEvent(event string, data interface{}) (webhooks.Payload, string, error) {
+	payload, err := webhooks.NewPayload(event, data)
+	if err != nil {
+		return payload, "", err
+	}
+	body, err := json.Marshal(payload)
+	return payload, string(body), err
+}
+
+// attemptWebhook sends a delivery once and records the outcome on it for
+// the caller to save, setting NextAttemptAt when the send failed and
+// attempts remain. Inactive endpoints fail the delivery without a request.
+func attemptWebhook(ctx context.Context, delivery *models.WebhookDelivery, webhook models.Webhook, policy config.WebhookRetryPolicy) {
+	if !webhook.Active && delivery.Event != models.WebhookEventPing {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = "endpoint is inactive"
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, policy.Timeout)
+	defer cancel()
+
+	response, err := webhooks.Send(ctx, webhookClient, webhook.URL, webhook.Secret, delivery.Event, delivery.EventID, []byte(delivery.Payload))
+
+	now := webhookNow()
+	delivery.Attempts++
+	delivery.ResponseStatus = response.StatusCode
+	delivery.ResponseBody = response.Body
+	delivery.NextAttemptAt = nil
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts < policy.MaxAttempts:
+		next := now.Add(utils.Backoff(delivery.Attempts, policy.BaseDelay, policy.MaxDelay))
+		delivery.Status = models.WebhookDeliveryRetrying
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = &next
+	default:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+	}
+}
+
+func saveWebhookDelivery(db *gorm.DB, delivery *models.WebhookDelivery) error {
+	return db.Model(delivery).
+		Select("status", "attempts", "response_status", "response_body", "error", "next_attempt_at", "delivered_at").
+		Updates(delivery).Error
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"backend-vercel-phone-review/config"
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"backend-vercel-phone-review/webhooks"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// useTestDB points config.DB at a fresh SQLite database for the test.
+func useTestDB(t *testing.T, tables ...interface{}) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+func TestWebhookDeliveryRetries(t *testing.T) {
+	const (
+		secret      = "whsec_test"
+		maxAttempts = 3
+		baseDelay   = 10 * time.Second
+		maxDelay    = time.Hour
+	)
+
+	tests := []struct {
+		name         string
+		failures     int
+		wantStatus   string
+		wantAttempts int
+		wantResponse int
+	}{
+		{"succeeds after retries", 2, models.WebhookDeliverySucceeded, 3, http.StatusOK},
+		{"gives up after the last attempt", maxAttempts, models.WebhookDeliveryFailed, maxAttempts, http.StatusServiceUnavailable},
+		{"succeeds at once", 0, models.WebhookDeliverySucceeded, 1, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDB(t, &models.Webhook{}, &models.WebhookDelivery{}, &models.OutboxJob{})
+			t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+			t.Setenv("WEBHOOK_RETRY_BASE_SECONDS", "10")
+			t.Setenv("WEBHOOK_RETRY_MAX_SECONDS", "3600")
+
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, _ := io.ReadAll(r.Body)
+				if err := webhooks.Verify(secret, r.Header.Get(webhooks.SignatureHeader), body, 0); err != nil {
+					t.Errorf("request %d: %v", requests, err)
+				}
+				if requests <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					io.WriteString(w, "busy")
+					return
+				}
+				io.WriteString(w, "ok")
+			}))
+			defer server.Close()
+
+			hook := models.Webhook{URL: server.URL, Events: []string{models.WebhookEventPhoneCreated}, Secret: secret, Active: true}
+			if err := config.DB.Create(&hook).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := dispatchWebhooks(config.DB, models.WebhookEventPhoneCreated, map[string]interface{}{"id": 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Jobs are enqueued at the real time, so the clock starts ahead
+			// of it to find them due
+			clock := &testClock{now: time.Now().Add(time.Hour)}
+			webhookNow = clock.Now
+			t.Cleanup(func() { webhookNow = time.Now })
+			worker := NewWorker()
+			worker.Now = clock.Now
+
+			ctx := context.Background()
+			if _, err := worker.RunOnce(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var delivery models.WebhookDelivery
+			for attempt := 1; attempt <= maxAttempts; attempt++ {
+				if _, err := worker.RunOnce(ctx); err != nil {
+					t.Fatal(err)
+				}
+				delivery = models.WebhookDelivery{}
+				if err := config.DB.Where("webhook_id = ?", hook.ID).First(&delivery).Error; err != nil {
+					t.Fatal(err)
+				}
+				if delivery.Attempts != attempt {
+					t.Fatalf("attempt %d: delivery.Attempts = %d", attempt, delivery.Attempts)
+				}
+				if delivery.Status != models.WebhookDeliveryRetrying {
+					break
+				}
+
+				want := clock.now.Add(utils.Backoff(attempt, baseDelay, maxDelay))
+				if delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(want) {
+					t.Fatalf("attempt %d: NextAttemptAt = %v, want %v", attempt, delivery.NextAttemptAt, want)
+				}
+				var next models.OutboxJob
+				if err := config.DB.Where("kind = ? AND status = ?", jobWebhookDeliver, models.OutboxJobPending).First(&next).Error; err != nil {
+					t.Fatalf("attempt %d: next attempt not queued: %v", attempt, err)
+				}
+				if !next.RunAt.Equal(want) {
+					t.Errorf("attempt %d: next attempt runs at %v, want %v", attempt, next.RunAt, want)
+				}
+
+				// Nothing runs before the backoff has passed
+				summary, err := worker.RunOnce(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if summary.Claimed != 0 {
+					t.Fatalf("attempt %d: %d jobs ran before the next attempt was due", attempt, summary.Claimed)
+				}
+				clock.now = want
+			}
+
+			if delivery.Status != tt.wantStatus || delivery.Attempts != tt.wantAttempts || delivery.ResponseStatus != tt.wantResponse {
+				t.Errorf("delivery = %s after %d attempts with response %d, want %s after %d with %d",
+					delivery.Status, delivery.Attempts, delivery.ResponseStatus, tt.wantStatus, tt.wantAttempts, tt.wantResponse)
+			}
+			if requests != tt.wantAttempts {
+				t.Errorf("receiver got %d requests, want %d", requests, tt.wantAttempts)
+			}
+			if delivery.NextAttemptAt != nil {
+				t.Errorf("NextAttemptAt = %v, want nil", delivery.NextAttemptAt)
+			}
+			if succeeded := tt.wantStatus == models.WebhookDeliverySucceeded; (delivery.DeliveredAt != nil) != succeeded {
+				t.Errorf("DeliveredAt = %v", delivery.DeliveredAt)
+			}
+			if tt.wantStatus == models.WebhookDeliveryFailed && delivery.Error == "" {
+				t.Error("failed delivery has no error")
+			}
+
+			var logged, pending int64
+			config.DB.Model(&models.WebhookDelivery{}).Count(&logged)
+			config.DB.Model(&models.OutboxJob{}).Where("status <> ?", models.OutboxJobDone).Count(&pending)
+			if logged != 1 || pending != 0 {
+				t.Errorf("%d deliveries logged and %d jobs left, want 1 and 0", logged, pending)
+			}
+		})
+	}
+}
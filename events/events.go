@@ -0,0 +1,50 @@
+// Package events fans out real-time updates to subscribers, such as the
+// Server-Sent Events streams, through a swappable Broker.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Event is a single update published on a topic. Name becomes the SSE event
+// name and Data its JSON payload.
+type Event struct {
+	Topic string          `json:"topic"`
+	Name  string          `json:"name"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Broker delivers published events to every current subscriber of their
+// topic. Delivery is best effort: a subscriber that falls behind misses
+// events rather than blocking publishers.
+type Broker interface {
+	Publish(event Event) error
+	// Subscribe returns a channel of the topic's events and a function that
+	// ends the subscription and closes the channel.
+	Subscribe(topic string) (<-chan Event, func())
+}
+
+// NewEvent builds an event with data encoded as JSON.
+func NewEvent(topic, name string, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Topic: topic, Name: name, Data: raw}, nil
+}
+
+// PhoneReviewsTopic carries reviews published for a phone.
+func PhoneReviewsTopic(phoneID uint) string {
+	return fmt.Sprintf("phones.%d.reviews", phoneID)
+}
+
+// ReviewCommentsTopic carries comments published on a review.
+func ReviewCommentsTopic(reviewID uint) string {
+	return fmt.Sprintf("reviews.%d.comments", reviewID)
+}
+
+// UserNotificationsTopic carries a user's new notifications.
+func UserNotificationsTopic(userID uint) string {
+	return fmt.Sprintf("users.%d.notifications", userID)
+}
@@ -0,0 +1,54 @@
+package events
+
+import "sync"
+
+// subscriberBuffer is how many events a subscriber can fall behind by
+// before further events are dropped for it.
+const subscriberBuffer = 16
+
+// Memory is a Broker that only reaches subscribers in the same process.
+type Memory struct {
+	mu     sync.RWMutex
+	topics map[string]map[chan Event]struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{topics: make(map[string]map[chan Event]struct{})}
+}
+
+func (m *Memory) Publish(event Event) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for ch := range m.topics[event.Topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	m.mu.Lock()
+	if m.topics[topic] == nil {
+		m.topics[topic] = make(map[chan Event]struct{})
+	}
+	m.topics[topic][ch] = struct{}{}
+	m.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			m.mu.Lock()
+			delete(m.topics[topic], ch)
+			if len(m.topics[topic]) == 0 {
+				delete(m.topics, topic)
+			}
+			m.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// postgresChannel is the LISTEN/NOTIFY channel every event travels on.
+const postgresChannel = "phone_review_events"
+
+// postgresPayloadLimit is the largest NOTIFY payload Postgres accepts.
+const postgresPayloadLimit = 8000
+
+var ErrEventTooLarge = errors.New("event is too large for postgres notify")
+
+// Postgres is a Broker that shares events between every instance connected
+// to the same database through LISTEN/NOTIFY. Each instance keeps a single
+// listening connection and fans the events it receives out locally.
+type Postgres struct {
+	db    *gorm.DB
+	local *Memory
+}
+
+// NewPostgres publishes through db and listens on a dedicated connection
+// opened from dsn, reconnecting whenever that connection drops.
+func NewPostgres(db *gorm.DB, dsn string) (*Postgres, error) {
+	conn, err := listen(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Postgres{db: db, local: NewMemory()}
+	go p.run(conn, dsn)
+	return p, nil
+}
+
+func (p *Postgres) Publish(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > postgresPayloadLimit {
+		return ErrEventTooLarge
+	}
+	return p.db.Exec("SELECT pg_notify(?, ?)", postgresChannel, string(payload)).Error
+}
+
+func (p *Postgres) Subscribe(topic string) (<-chan Event, func()) {
+	return p.local.Subscribe(topic)
+}
+
+func (p *Postgres) run(conn *pgx.Conn, dsn string) {
+	backoff := time.Second
+	for {
+		for conn != nil {
+			notification, err := conn.WaitForNotification(context.Background())
+			if err != nil {
+				log.Printf("Lost postgres event listener: %v", err)
+				conn.Close(context.Background())
+				conn = nil
+				break
+			}
+			backoff = time.Second
+
+			var event Event
+			if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+				log.Printf("Dropping malformed event: %v", err)
+				continue
+			}
+			p.local.Publish(event)
+		}
+
+		time.Sleep(backoff)
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+
+		var err error
+		if conn, err = listen(dsn); err != nil {
+			log.Printf("Could not reconnect postgres event listener: %v", err)
+		}
+	}
+}
+
+func listen(dsn string) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(context.Background(), dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(context.Background(), "LISTEN "+postgresChannel); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
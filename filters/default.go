@@ -0,0 +1,41 @@
+package filters
+
+import (
+	"backend-vercel-phone-review/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultProfanity   = "fuck,fucking,shit,bitch,bastard,asshole,dickhead,cunt"
+	defaultSpamPhrases = "buy now,click here,free money,work from home,limited offer,whatsapp me"
+)
+
+// Default builds the chain run on reviews and comments. The word lists and
+// link limit can be overridden with PROFANITY_WORDS, SPAM_PHRASES and
+// SPAM_MAX_LINKS.
+func Default() Chain {
+	maxLinks, err := strconv.Atoi(utils.Getenv("SPAM_MAX_LINKS", "2"))
+	if err != nil || maxLinks < 0 {
+		maxLinks = 2
+	}
+
+	return Chain{
+		&RepetitionFilter{MinUniqueRatio: 0.3},
+		&SpamFilter{
+			MaxLinks: maxLinks,
+			Phrases:  splitList(utils.Getenv("SPAM_PHRASES", defaultSpamPhrases)),
+		},
+		NewProfanityFilter(splitList(utils.Getenv("PROFANITY_WORDS", defaultProfanity))),
+	}
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,94 @@
+// Package filters runs user generated text through a chain of content
+// filters and settles on a single verdict for it.
+package filters
+
+const (
+	VerdictAllow  = "allow"
+	VerdictMask   = "mask"
+	VerdictHold   = "hold"
+	VerdictReject = "reject"
+)
+
+// severity orders verdicts so the strictest one of a chain wins.
+var severity = map[string]int{
+	VerdictAllow:  0,
+	VerdictMask:   1,
+	VerdictHold:   2,
+	VerdictReject: 3,
+}
+
+// Input is the text being checked together with the author's recent posts
+// of the same kind, which repetition checks compare against.
+type Input struct {
+	Text   string
+	Recent []string
+}
+
+// Outcome is what a single filter decided. Text is the possibly rewritten
+// text handed to the next filter.
+type Outcome struct {
+	Verdict string
+	Text    string
+	Reason  string
+}
+
+type Filter interface {
+	Apply(input Input) Outcome
+}
+
+// Result is the combined decision of a chain.
+type Result struct {
+	Verdict string   `json:"verdict"`
+	Text    string   `json:"-"`
+	Reasons []string `json:"reasons"`
+}
+
+type Chain []Filter
+
+// Run applies every filter in order, feeding each one the text produced by
+// the previous one. It stops early once a filter rejects the text.
+func (ch Chain) Run(input Input) Result {
+	result := Result{Verdict: VerdictAllow, Text: input.Text, Reasons: []string{}}
+
+	for _, filter := range ch {
+		outcome := filter.Apply(Input{Text: result.Text, Recent: input.Recent})
+		if outcome.Text != "" {
+			result.Text = outcome.Text
+		}
+		if outcome.Verdict == "" || outcome.Verdict == VerdictAllow {
+			continue
+		}
+
+		if severity[outcome.Verdict] > severity[result.Verdict] {
+			result.Verdict = outcome.Verdict
+		}
+		if outcome.Reason != "" {
+			result.Reasons = append(result.Reasons, outcome.Reason)
+		}
+		if result.Verdict == VerdictReject {
+			break
+		}
+	}
+
+	return result
+}
+
+// Combine merges the results of several texts checked together, such as
+// the parts of one post, into one: the strictest verdict wins and every
+// distinct reason is kept. The combined Text is empty.
+func Combine(results ...Result) Result {
+	combined := Result{Verdict: VerdictAllow, Reasons: []string{}}
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if severity[result.Verdict] > severity[combined.Verdict] {
+			combined.Verdict = result.Verdict
+		}
+		for _, reason := range result.Reasons {
+			if !seen[reason] {
+				seen[reason] = true
+				combined.Reasons = append(combined.Reasons, reason)
+			}
+		}
+	}
+	return combined
+}
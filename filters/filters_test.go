@@ -0,0 +1,82 @@
+package filters
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testChain() Chain {
+	return Chain{
+		&RepetitionFilter{MinUniqueRatio: 0.3},
+		&SpamFilter{MaxLinks: 2, Phrases: []string{"buy now"}},
+		NewProfanityFilter([]string{"shit", "damn"}),
+	}
+}
+
+func TestChainRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		input Input
+		want  Result
+	}{
+		{
+			"clean",
+			Input{Text: "Solid phone with a great camera."},
+			Result{Verdict: VerdictAllow, Text: "Solid phone with a great camera.", Reasons: []string{}},
+		},
+		{
+			"masked",
+			Input{Text: "The battery is shit, DAMN it."},
+			Result{Verdict: VerdictMask, Text: "The battery is s***, D*** it.", Reasons: []string{"profanity masked"}},
+		},
+		{
+			"held and masked",
+			Input{Text: "Buy now, this shit is cheap"},
+			Result{Verdict: VerdictHold, Text: "Buy now, this s*** is cheap", Reasons: []string{`contains spam phrase "buy now"`, "profanity masked"}},
+		},
+		{
+			"duplicate stops the chain",
+			Input{Text: "Great phone, shit speaker!", Recent: []string{"great phone shit speaker"}},
+			Result{Verdict: VerdictReject, Text: "Great phone, shit speaker!", Reasons: []string{"duplicate of a recent post"}},
+		},
+		{
+			"mostly links",
+			Input{Text: "https://a.example https://b.example deals"},
+			Result{Verdict: VerdictReject, Text: "https://a.example https://b.example deals", Reasons: []string{"text is mostly links"}},
+		},
+		{
+			"repetitive",
+			Input{Text: "good good good good good good good good good good phone"},
+			Result{Verdict: VerdictHold, Text: "good good good good good good good good good good phone", Reasons: []string{"highly repetitive text"}},
+		},
+		{
+			"shouting",
+			Input{Text: "THIS PHONE IS THE WORST I HAVE EVER OWNED"},
+			Result{Verdict: VerdictHold, Text: "THIS PHONE IS THE WORST I HAVE EVER OWNED", Reasons: []string{"mostly upper case"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testChain().Run(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	got := Combine(
+		Result{Verdict: VerdictMask, Reasons: []string{"profanity masked"}},
+		Result{Verdict: VerdictAllow, Reasons: []string{}},
+		Result{Verdict: VerdictHold, Reasons: []string{"mostly upper case", "profanity masked"}},
+	)
+	want := Result{Verdict: VerdictHold, Reasons: []string{"profanity masked", "mostly upper case"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Combine() = %+v, want %+v", got, want)
+	}
+
+	if got := Combine(); !reflect.DeepEqual(got, Result{Verdict: VerdictAllow, Reasons: []string{}}) {
+		t.Errorf("Combine() of nothing = %+v", got)
+	}
+}
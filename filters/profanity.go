@@ -0,0 +1,42 @@
+package filters
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ProfanityFilter masks listed words, matched case-insensitively on word
+// boundaries, keeping their first letter.
+type ProfanityFilter struct {
+	pattern *regexp.Regexp
+}
+
+func NewProfanityFilter(words []string) *ProfanityFilter {
+	var quoted []string
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return &ProfanityFilter{}
+	}
+
+	return &ProfanityFilter{
+		pattern: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`),
+	}
+}
+
+func (f *ProfanityFilter) Apply(input Input) Outcome {
+	if f.pattern == nil || !f.pattern.MatchString(input.Text) {
+		return Outcome{Verdict: VerdictAllow}
+	}
+
+	masked := f.pattern.ReplaceAllStringFunc(input.Text, func(word string) string {
+		runes := []rune(word)
+		return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+	})
+
+	return Outcome{Verdict: VerdictMask, Text: masked, Reason: "profanity masked"}
+}
@@ -0,0 +1,43 @@
+package filters
+
+import "strings"
+
+// RepetitionFilter rejects text the author has already posted and holds
+// text that repeats the same words over and over.
+type RepetitionFilter struct {
+	// MinUniqueRatio is the smallest share of distinct words a longer
+	// text may have before it is held
+	MinUniqueRatio float64
+}
+
+func (f *RepetitionFilter) Apply(input Input) Outcome {
+	normalized := normalize(input.Text)
+	for _, recent := range input.Recent {
+		if normalized != "" && normalize(recent) == normalized {
+			return Outcome{Verdict: VerdictReject, Reason: "duplicate of a recent post"}
+		}
+	}
+
+	words := strings.Fields(normalized)
+	if len(words) < 10 {
+		return Outcome{Verdict: VerdictAllow}
+	}
+
+	unique := make(map[string]bool)
+	for _, word := range words {
+		unique[word] = true
+	}
+	if float64(len(unique))/float64(len(words)) < f.MinUniqueRatio {
+		return Outcome{Verdict: VerdictHold, Reason: "highly repetitive text"}
+	}
+
+	return Outcome{Verdict: VerdictAllow}
+}
+
+// normalize lower-cases text and strips punctuation so trivially altered
+// copies compare equal.
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}), " ")
+}
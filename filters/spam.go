@@ -0,0 +1,57 @@
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// SpamFilter holds text that looks like link spam or shouting for
+// moderation, and rejects text that is little more than a list of links.
+type SpamFilter struct {
+	// MaxLinks is how many links are tolerated before the text is held
+	MaxLinks int
+	// Phrases are spam phrases that hold the text when present
+	Phrases []string
+}
+
+func (f *SpamFilter) Apply(input Input) Outcome {
+	links := len(linkPattern.FindAllString(input.Text, -1))
+	if links >= 2 && links*2 >= len(strings.Fields(input.Text)) {
+		return Outcome{Verdict: VerdictReject, Reason: "text is mostly links"}
+	}
+	if links > f.MaxLinks {
+		return Outcome{Verdict: VerdictHold, Reason: fmt.Sprintf("contains %d links", links)}
+	}
+
+	lower := strings.ToLower(input.Text)
+	for _, phrase := range f.Phrases {
+		if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+			return Outcome{Verdict: VerdictHold, Reason: fmt.Sprintf("contains spam phrase %q", phrase)}
+		}
+	}
+
+	if shouting(input.Text) {
+		return Outcome{Verdict: VerdictHold, Reason: "mostly upper case"}
+	}
+
+	return Outcome{Verdict: VerdictAllow}
+}
+
+// shouting reports whether a text of some length is written mostly in
+// capital letters.
+func shouting(text string) bool {
+	var letters, upper int
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= 20 && upper*10 >= letters*8
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSink writes every message to its own .eml file in Dir instead of
+// sending it, so mail can be inspected during development and tests.
+type FileSink struct {
+	Dir string
+}
+
+func (s FileSink) Send(ctx context.Context, msg Message) error {
+	raw, err := Build(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(s.Dir, name), raw, 0o644)
+}
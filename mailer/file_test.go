@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	sink := FileSink{Dir: dir}
+
+	messages := []Message{
+		{From: "digest@example.com", To: "ada@example.com", Subject: "Your weekly digest", Text: "Hello Ada", HTML: "<p>Hello Ada</p>"},
+		{From: "digest@example.com", To: "bob@example.com", Subject: "Your weekly digest", Text: "Hello Bob", HTML: "<p>Hello Bob</p>"},
+	}
+	for _, msg := range messages {
+		if err := sink.Send(context.Background(), msg); err != nil {
+			t.Fatalf("Send() = %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(messages) {
+		t.Fatalf("wrote %d files, want %d", len(files), len(messages))
+	}
+
+	var recipients []string
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := string(raw)
+		if !strings.Contains(content, "Subject: Your weekly digest") {
+			t.Errorf("%s has no subject:\n%s", file, content)
+		}
+		for _, msg := range messages {
+			if strings.Contains(content, "To: "+msg.To) {
+				recipients = append(recipients, msg.To)
+			}
+		}
+	}
+	if len(recipients) != len(messages) {
+		t.Errorf("found recipients %v", recipients)
+	}
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTP sends messages through an SMTP server at Addr ("host:port"). It
+// authenticates with PLAIN when Username is set, which net/smtp only allows
+// over TLS or to localhost.
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+}
+
+func (s SMTP) Send(ctx context.Context, msg Message) error {
+	raw, err := Build(msg)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, auth, from.Address, []string{to.Address}, raw)
+}
@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFiles, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/*.html"))
+)
+
+// Render fills in the plain text and HTML versions of a template, found as
+// templates/<name>.txt and templates/<name>.html.
+func Render(name string, data interface{}) (text, html string, err error) {
+	var textBody, htmlBody bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&textBody, name+".txt", data); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&htmlBody, name+".html", data); err != nil {
+		return "", "", err
+	}
+	return textBody.String(), htmlBody.String(), nil
+}
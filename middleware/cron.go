@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"backend-vercel-phone-review/config"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireCronSecret lets the request through only when it carries the
+// CRON_SECRET bearer token, as sent by the Vercel cron scheduler.
+func RequireCronSecret() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := config.CronSecret()
+		if secret == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "cron secret not configured"})
+			c.Abort()
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+secret)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid cron secret"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
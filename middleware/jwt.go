@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"backend-vercel-phone-review/models"
 	"net/http"
 	"os"
 	"strings"
@@ -34,8 +35,9 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Stream tickets only open the notification stream
 		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
+		if !ok || !token.Valid || claims.VerifyAudience(models.StreamTicketAudience, true) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization token"})
 			c.Abort()
 			return
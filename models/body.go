@@ -11,8 +11,9 @@ type RegistRequest struct {
 }
 
 type PhoneRequest struct {
-	Brand string `json:"brand" binding:"required"`
-	Name  string `json:"name" binding:"required"`
+	Brand string   `json:"brand" binding:"required,max=100,nohtml"`
+	Name  string   `json:"name" binding:"required,max=100,nohtml"`
+	Price *float64 `json:"price" binding:"omitempty,gt=0"`
 }
 
 type UserResponse struct {
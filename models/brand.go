@@ -0,0 +1,32 @@
+package models
+
+import "gorm.io/gorm"
+
+// Brand is a phone maker. Phones point at it through BrandID and keep its
+// name in Phone.Brand. The slug is fixed once the brand exists, since URLs
+// and brand follows refer to it.
+type Brand struct {
+	gorm.Model  `swaggerignore:"true"`
+	Slug        string `json:"slug" gorm:"size:191;uniqueIndex"`
+	Name        string `json:"name" gorm:"size:100"`
+	LogoURL     string `json:"logo_url"`
+	Country     string `json:"country" gorm:"size:100"`
+	Description string `json:"description" gorm:"type:text"`
+}
+
+type BrandRequest struct {
+	Name        string `json:"name" binding:"required,max=100,nohtml"`
+	LogoURL     string `json:"logo_url" binding:"omitempty,http_url,max=500"`
+	Country     string `json:"country" binding:"max=100,nohtml"`
+	Description string `json:"description" binding:"max=2000,nohtml"`
+}
+
+// BrandStats is a brand with the aggregates of the published reviews of
+// all its phones. Dimensions are only filled for a single brand.
+type BrandStats struct {
+	Brand
+	PhoneCount    int64            `json:"phone_count"`
+	ReviewCount   int64            `json:"review_count"`
+	AverageRating float64          `json:"average_rating"`
+	Dimensions    []DimensionStats `json:"dimensions,omitempty" gorm:"-"`
+}
@@ -2,9 +2,30 @@ package models
 
 import "gorm.io/gorm"
 
+const (
+	CommentStatusPending   = "pending"
+	CommentStatusPublished = "published"
+	CommentStatusHidden    = "hidden"
+)
+
 type Comment struct {
-	gorm.Model `swaggerignore:"true"`
-	ReviewID   uint   `json:"review_id"`
-	UserID     uint   `json:"user_id"`
-	Content    string `json:"content"`
+	gorm.Model    `swaggerignore:"true"`
+	ReviewID      uint      `json:"review_id"`
+	UserID        uint      `json:"user_id"`
+	ParentID      *uint     `json:"parent_id" gorm:"index"`
+	Depth         int       `json:"depth" swaggerignore:"true"`
+	Content       string    `json:"content" binding:"required,max=2000,nohtml"`
+	Status        string    `json:"status" gorm:"default:published;index" swaggerignore:"true"`
+	ModeratedBy   *uint     `json:"moderated_by,omitempty" swaggerignore:"true"`
+	FilterVerdict string    `json:"filter_verdict" swaggerignore:"true"`
+	FilterReasons []string  `json:"filter_reasons" gorm:"serializer:json" swaggerignore:"true"`
+	Mentions      []Mention `json:"mentions" gorm:"foreignKey:CommentID" swaggerignore:"true"`
+	Rendered      string    `json:"rendered_content" gorm:"-" swaggerignore:"true"`
+}
+
+type CommentThread struct {
+	Comment
+	ReplyCount int              `json:"reply_count"`
+	Removed    bool             `json:"removed,omitempty"`
+	Replies    []*CommentThread `json:"replies,omitempty"`
 }
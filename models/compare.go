@@ -0,0 +1,40 @@
+package models
+
+// CompareValue is one phone's value in a row of a comparison. Best marks
+// the phones holding the winning value of a spec that ranks its values.
+type CompareValue struct {
+	PhoneID uint        `json:"phone_id"`
+	Value   interface{} `json:"value"`
+	Best    bool        `json:"best"`
+}
+
+// CompareRow lines up the values of one spec, one per compared phone in the
+// order they were asked for. Differs is set when they are not all equal.
+type CompareRow struct {
+	Key      string         `json:"key"`
+	Label    string         `json:"label"`
+	DataType string         `json:"data_type"`
+	Unit     string         `json:"unit,omitempty"`
+	Values   []CompareValue `json:"values"`
+	Differs  bool           `json:"differs"`
+}
+
+type CompareGroup struct {
+	Group string       `json:"group"`
+	Rows  []CompareRow `json:"rows"`
+}
+
+// CategoryWinner names the phone that comes out ahead in a category, or no
+// phone when it is a tie.
+type CategoryWinner struct {
+	Category string `json:"category"`
+	PhoneID  *uint  `json:"phone_id"`
+	Summary  string `json:"summary"`
+}
+
+type PhoneComparison struct {
+	Phones  []Phone          `json:"phones"`
+	Specs   []CompareGroup   `json:"specs"`
+	Ratings []PhoneStats     `json:"ratings"`
+	Winners []CategoryWinner `json:"winners"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSubscription opts a user in to a periodic email summing up new
+// reviews on the phones they follow and replies to their reviews and
+// comments. Each digest covers the time since LastDigestAt. Digests are
+// only sent once the address has been confirmed through the link emailed
+// to it, which sets ConfirmedAt.
+type DigestSubscription struct {
+	gorm.Model         `swaggerignore:"true"`
+	UserID             uint       `json:"user_id" gorm:"uniqueIndex"`
+	Email              string     `json:"email"`
+	Frequency          string     `json:"frequency"`
+	ConfirmedAt        *time.Time `json:"confirmed_at"`
+	LastDigestAt       time.Time  `json:"last_digest_at"`
+	NextDigestAt       time.Time  `json:"next_digest_at" gorm:"index"`
+	LastSentAt         *time.Time `json:"last_sent_at"`
+	ConfirmationSentAt *time.Time `json:"confirmation_sent_at"`
+}
+
+type DigestRequest struct {
+	Email     string `json:"email" binding:"required,email,max=254"`
+	Frequency string `json:"frequency" binding:"required,oneof=daily weekly"`
+}
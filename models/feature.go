@@ -2,9 +2,34 @@ package models
 
 import "gorm.io/gorm"
 
+// Feature is a free text fact about a phone. When its name matches a spec
+// of the registry, SpecKey names that spec and a numeric spec's Value and
+// Unit hold the details converted to the spec's unit.
 type Feature struct {
 	gorm.Model `swaggerignore:"true"`
-	Name       string `json:"name"`
-	Details    string `json:"details"`
-	PhoneID    uint   `json:"phone_id" swaggerignore:"true"`
+	Name       string   `json:"name" binding:"required,max=100,nohtml"`
+	Details    string   `json:"details" binding:"required,max=1000,nohtml"`
+	PhoneID    uint     `json:"phone_id" swaggerignore:"true"`
+	SpecKey    string   `json:"spec_key,omitempty" swaggerignore:"true"`
+	Value      *float64 `json:"value,omitempty" swaggerignore:"true"`
+	Unit       string   `json:"unit,omitempty" swaggerignore:"true"`
+	ParseError string   `json:"parse_error,omitempty" swaggerignore:"true"`
+}
+
+// FeatureParseFailure is a feature whose details could not be read as a
+// value of the spec its name matched.
+type FeatureParseFailure struct {
+	FeatureID uint   `json:"feature_id"`
+	PhoneID   uint   `json:"phone_id"`
+	Name      string `json:"name"`
+	Details   string `json:"details"`
+	SpecKey   string `json:"spec_key"`
+	Error     string `json:"error"`
+}
+
+type SpecParseReport struct {
+	Features int                   `json:"features"`
+	Matched  int                   `json:"matched"`
+	Parsed   int                   `json:"parsed"`
+	Failed   []FeatureParseFailure `json:"failed"`
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	FeedItemReview  = "review"
+	FeedItemComment = "comment"
+)
+
+// FeedItem is one entry of a user's activity feed. Exactly one of Review
+// and Comment is set, matching Type.
+type FeedItem struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	ActorID   uint      `json:"actor_id"`
+	Review    *Review   `json:"review,omitempty"`
+	Comment   *Comment  `json:"comment,omitempty"`
+}
+
+type FeedPage struct {
+	Items      []FeedItem `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	FollowTargetPhone = "phone"
+	FollowTargetBrand = "brand"
+	FollowTargetUser  = "user"
+)
+
+// Follow subscribes a user to updates about a phone, a brand or another
+// user. Phones and users are identified by TargetID and brands by their
+// slug in Brand.
+type Follow struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint   `json:"user_id" gorm:"uniqueIndex:idx_follows_user_target"`
+	TargetType string `json:"target_type" gorm:"uniqueIndex:idx_follows_user_target;index:idx_follows_target"`
+	TargetID   uint   `json:"target_id" gorm:"uniqueIndex:idx_follows_user_target;index:idx_follows_target"`
+	Brand      string `json:"brand,omitempty" gorm:"uniqueIndex:idx_follows_user_target;index:idx_follows_target;size:191"`
+}
+
+type BrandFollow struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type FollowedUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+type FollowList struct {
+	Phones []Phone        `json:"phones"`
+	Brands []BrandFollow  `json:"brands"`
+	Users  []FollowedUser `json:"users"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+type Mention struct {
+	gorm.Model `swaggerignore:"true"`
+	CommentID  uint   `json:"comment_id" gorm:"uniqueIndex:idx_mentions_comment_user"`
+	UserID     uint   `json:"user_id" gorm:"uniqueIndex:idx_mentions_comment_user"`
+	Username   string `json:"username"`
+}
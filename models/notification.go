@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	NotificationComment    = "comment"
+	NotificationReply      = "reply"
+	NotificationMention    = "mention"
+	NotificationVote       = "vote"
+	NotificationModeration = "moderation"
+	NotificationFollow     = "follow"
+)
+
+// NotificationTypes lists every kind of notification a user can turn off.
+var NotificationTypes = []string{
+	NotificationComment,
+	NotificationReply,
+	NotificationMention,
+	NotificationVote,
+	NotificationModeration,
+	NotificationFollow,
+}
+
+type Notification struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint       `json:"user_id" gorm:"index:idx_notifications_user_read"`
+	ActorID    uint       `json:"actor_id"`
+	Type       string     `json:"type"`
+	PhoneID    *uint      `json:"phone_id"`
+	ReviewID   *uint      `json:"review_id"`
+	CommentID  *uint      `json:"comment_id"`
+	Message    string     `json:"message"`
+	ReadAt     *time.Time `json:"read_at" gorm:"index:idx_notifications_user_read"`
+}
+
+// NotificationPreference records that a user turned a notification type on
+// or off. Types without a preference are on.
+type NotificationPreference struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint   `json:"user_id" gorm:"uniqueIndex:idx_notification_preferences_user_type"`
+	Type       string `json:"type" gorm:"uniqueIndex:idx_notification_preferences_user_type"`
+	Enabled    bool   `json:"enabled"`
+}
+
+type NotificationPage struct {
+	Items       []Notification `json:"items"`
+	UnreadCount int64          `json:"unread_count"`
+	NextCursor  string         `json:"next_cursor,omitempty"`
+}
+
+// StreamTicketAudience marks the short-lived tokens that open the
+// notification stream, which are good for nothing else.
+const StreamTicketAudience = "notifications-stream"
+
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	OutboxJobPending = "pending"
+	OutboxJobRunning = "running"
+	OutboxJobDone    = "done"
+	OutboxJobDead    = "dead"
+)
+
+// OutboxJob is a side effect written in the same transaction as the change
+// that caused it and carried out later by the worker.
+type OutboxJob struct {
+	gorm.Model  `swaggerignore:"true"`
+	Kind        string     `json:"kind" gorm:"index"`
+	Payload     string     `json:"payload" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"default:pending;index:idx_outbox_jobs_due"`
+	RunAt       time.Time  `json:"run_at" gorm:"index:idx_outbox_jobs_due"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	LockedUntil *time.Time `json:"locked_until"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	CompletedAt *time.Time `json:"completed_at"`
+	// IdempotencyKey, when set, makes enqueueing the same job twice a no-op
+	IdempotencyKey *string `json:"idempotency_key" gorm:"uniqueIndex;size:191"`
+}
+
+type OutboxJobPage struct {
+	Items      []OutboxJob `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// JobRunSummary is what one pass of the worker did.
+type JobRunSummary struct {
+	Claimed int `json:"claimed"`
+	Done    int `json:"done"`
+	Retried int `json:"retried"`
+	Dead    int `json:"dead"`
+}
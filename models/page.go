@@ -0,0 +1,11 @@
+package models
+
+type ReviewPage struct {
+	Items      []Review `json:"items"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type CommentPage struct {
+	Items      []CommentThread `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
@@ -4,8 +4,27 @@ import "gorm.io/gorm"
 
 type Phone struct {
 	gorm.Model `swaggerignore:"true"`
-	Name       string    `json:"name"`
-	Brand      string    `json:"brand"`
-	Features   []Feature `json:"features" gorm:"foreignKey:PhoneID"`
-	Reviews    []Review  `json:"reviews" gorm:"foreignKey:PhoneID"`
+	Name       string      `json:"name" binding:"required,max=100,nohtml"`
+	Brand      string      `json:"brand" binding:"required,max=100,nohtml"`
+	BrandID    uint        `json:"brand_id" gorm:"index"`
+	Price      *float64    `json:"price" binding:"omitempty,gt=0"`
+	Features   []Feature   `json:"features" gorm:"foreignKey:PhoneID"`
+	Specs      []SpecGroup `json:"specs,omitempty" gorm:"-"`
+	Variants   []Variant   `json:"variants,omitempty" gorm:"-"`
+	Reviews    []Review    `json:"reviews" gorm:"foreignKey:PhoneID"`
+}
+
+type PointCount struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+type PhoneStats struct {
+	PhoneID       uint             `json:"phone_id"`
+	ReviewCount   int64            `json:"review_count"`
+	AverageRating float64          `json:"average_rating"`
+	Dimensions    []DimensionStats `json:"dimensions"`
+	Variants      []VariantStats   `json:"variants"`
+	TopPros       []PointCount     `json:"top_pros"`
+	TopCons       []PointCount     `json:"top_cons"`
 }
@@ -5,6 +5,6 @@ import "gorm.io/gorm"
 type Profile struct {
 	gorm.Model `swaggerignore:"true"`
 	UserID     uint   `json:"user_id"`
-	FullName   string `json:"full_name"`
-	Bio        string `json:"bio"`
+	FullName   string `json:"full_name" binding:"max=100,nohtml"`
+	Bio        string `json:"bio" binding:"max=1000,nohtml"`
 }
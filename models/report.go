@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReportTargetReview  = "review"
+	ReportTargetComment = "comment"
+
+	ReportStatusOpen      = "open"
+	ReportStatusDismissed = "dismissed"
+	ReportStatusUpheld    = "upheld"
+)
+
+type Report struct {
+	gorm.Model `swaggerignore:"true"`
+	TargetType string `json:"target_type" gorm:"uniqueIndex:idx_reports_target_reporter;index:idx_reports_target"`
+	TargetID   uint   `json:"target_id" gorm:"uniqueIndex:idx_reports_target_reporter;index:idx_reports_target"`
+	ReporterID uint   `json:"reporter_id" gorm:"uniqueIndex:idx_reports_target_reporter"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details"`
+	Status     string `json:"status" gorm:"default:open;index"`
+}
+
+type ReportRequest struct {
+	Reason  string `json:"reason" binding:"required,oneof=spam abuse harassment off_topic misinformation other"`
+	Details string `json:"details" binding:"max=500,nohtml"`
+}
+
+type ReportTriage struct {
+	TargetType     string         `json:"target_type"`
+	TargetID       uint           `json:"target_id"`
+	TargetStatus   string         `json:"target_status"`
+	Content        string         `json:"content"`
+	OpenReports    int            `json:"open_reports"`
+	Reasons        map[string]int `json:"reasons"`
+	FirstReportAt  time.Time      `json:"first_report_at"`
+	LatestReportAt time.Time      `json:"latest_report_at"`
+}
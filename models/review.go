@@ -1,12 +1,48 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReviewStatusPending   = "pending"
+	ReviewStatusPublished = "published"
+	ReviewStatusRejected  = "rejected"
+	ReviewStatusHidden    = "hidden"
+)
 
 type Review struct {
-	gorm.Model `swaggerignore:"true"`
-	PhoneID    uint      `json:"phone_id"`
-	UserID     uint      `json:"user_id"`
-	Rating     int       `json:"rating"`
-	Content    string    `json:"content"`
-	Comments   []Comment `json:"comments" gorm:"foreignKey:ReviewID" swaggerignore:"true"`
+	gorm.Model       `swaggerignore:"true"`
+	PhoneID          uint        `json:"phone_id" gorm:"uniqueIndex:idx_reviews_user_phone"`
+	UserID           uint        `json:"user_id" gorm:"uniqueIndex:idx_reviews_user_phone"`
+	VariantID        *uint       `json:"variant_id" gorm:"index"`
+	Rating           int         `json:"rating" binding:"rating"`
+	Content          string      `json:"content" binding:"required,min=10,max=5000,nohtml"`
+	Language         string      `json:"language" gorm:"size:35;index" binding:"omitempty,bcp47_language_tag"`
+	Verified         bool        `json:"verified" gorm:"index" swaggerignore:"true"`
+	Pros             []string    `json:"pros" gorm:"serializer:json" binding:"max=10,dive,required,max=100,nohtml"`
+	Cons             []string    `json:"cons" gorm:"serializer:json" binding:"max=10,dive,required,max=100,nohtml"`
+	SubRatings       []SubRating `json:"sub_ratings" gorm:"foreignKey:ReviewID" binding:"dive"`
+	HelpfulCount     int         `json:"helpful_count" gorm:"->;default:0"`
+	NotHelpfulCount  int         `json:"not_helpful_count" gorm:"->;default:0"`
+	Edited           bool        `json:"edited"`
+	EditedAt         *time.Time  `json:"edited_at"`
+	Status           string      `json:"status" gorm:"default:published;index"`
+	ModerationReason string      `json:"moderation_reason,omitempty"`
+	ModeratedBy      *uint       `json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time  `json:"moderated_at,omitempty"`
+	FilterVerdict    string      `json:"filter_verdict" swaggerignore:"true"`
+	FilterReasons    []string    `json:"filter_reasons" gorm:"serializer:json" swaggerignore:"true"`
+	Comments         []Comment   `json:"comments" gorm:"foreignKey:ReviewID" swaggerignore:"true"`
+	MoreComments     string      `json:"more_comments_cursor,omitempty" gorm:"-" swaggerignore:"true"`
+}
+
+type VerifyRequest struct {
+	Verified *bool `json:"verified" binding:"required"`
+}
+
+type ModerationRequest struct {
+	Reason string `json:"reason" binding:"required,max=500,nohtml"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"backend-vercel-phone-review/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReviewRevision struct {
+	gorm.Model `swaggerignore:"true"`
+	ReviewID   uint           `json:"review_id" gorm:"uniqueIndex:idx_review_revisions_review_revision"`
+	Revision   int            `json:"revision" gorm:"uniqueIndex:idx_review_revisions_review_revision"`
+	VariantID  *uint          `json:"variant_id"`
+	Rating     int            `json:"rating"`
+	SubRatings map[string]int `json:"sub_ratings" gorm:"serializer:json"`
+	Content    string         `json:"content"`
+	Pros       []string       `json:"pros" gorm:"serializer:json"`
+	Cons       []string       `json:"cons" gorm:"serializer:json"`
+}
+
+type RevisionResponse struct {
+	Revision       int            `json:"revision"`
+	VariantID      *uint          `json:"variant_id"`
+	Rating         int            `json:"rating"`
+	PreviousRating *int           `json:"previous_rating,omitempty"`
+	SubRatings     map[string]int `json:"sub_ratings"`
+	Content        string         `json:"content"`
+	Pros           []string       `json:"pros"`
+	Cons           []string       `json:"cons"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Diff           []utils.DiffOp `json:"diff,omitempty"`
+}
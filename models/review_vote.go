@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+type ReviewVote struct {
+	gorm.Model `swaggerignore:"true"`
+	ReviewID   uint `json:"review_id" gorm:"uniqueIndex:idx_review_votes_review_user"`
+	UserID     uint `json:"user_id" gorm:"uniqueIndex:idx_review_votes_review_user"`
+	Helpful    bool `json:"helpful"`
+}
+
+type VoteRequest struct {
+	Helpful *bool `json:"helpful" binding:"required"`
+}
+
+type VoteSummary struct {
+	ReviewID        uint  `json:"review_id"`
+	HelpfulCount    int   `json:"helpful_count"`
+	NotHelpfulCount int   `json:"not_helpful_count"`
+	MyVote          *bool `json:"my_vote"`
+}
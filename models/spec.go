@@ -0,0 +1,75 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	SpecNumber  = "number"
+	SpecInteger = "integer"
+	SpecBoolean = "boolean"
+	SpecText    = "text"
+	SpecEnum    = "enum"
+
+	SpecHigherIsBetter = "higher"
+	SpecLowerIsBetter  = "lower"
+)
+
+// SpecGroups lists the groups specs are shown in, in display order.
+var SpecGroups = []string{"Display", "Camera", "Battery", "Performance", "Body", "Connectivity", "Software"}
+
+// SpecDefinition is an entry of the spec registry: a typed specification
+// every phone may have a value for, such as the battery capacity in mAh.
+// Better tells comparisons which values win: "higher", "lower" or empty
+// when neither does.
+type SpecDefinition struct {
+	gorm.Model `swaggerignore:"true"`
+	Key        string   `json:"key" gorm:"size:64;uniqueIndex"`
+	Label      string   `json:"label"`
+	DataType   string   `json:"data_type"`
+	Unit       string   `json:"unit"`
+	Group      string   `json:"group"`
+	Options    []string `json:"options,omitempty" gorm:"serializer:json"`
+	Better     string   `json:"better,omitempty"`
+	Position   int      `json:"position"`
+}
+
+type SpecDefinitionRequest struct {
+	Label    string   `json:"label" binding:"required,max=100,nohtml"`
+	DataType string   `json:"data_type" binding:"required,oneof=number integer boolean text enum"`
+	Unit     string   `json:"unit" binding:"max=20,nohtml"`
+	Group    string   `json:"group" binding:"required,oneof=Display Camera Battery Performance Body Connectivity Software"`
+	Options  []string `json:"options" binding:"max=50,dive,required,max=100,nohtml"`
+	Better   string   `json:"better" binding:"omitempty,oneof=higher lower"`
+	Position int      `json:"position"`
+}
+
+type CreateSpecDefinitionRequest struct {
+	Key string `json:"key" binding:"required,max=64"`
+	SpecDefinitionRequest
+}
+
+// SpecValue is the value of one spec for one phone, kept in the column
+// that matches the spec's data type. FeatureID is the feature it was parsed
+// from, and nil for values set on the phone directly.
+type SpecValue struct {
+	gorm.Model       `swaggerignore:"true"`
+	PhoneID          uint     `json:"phone_id" gorm:"uniqueIndex:idx_spec_values_phone_spec"`
+	SpecDefinitionID uint     `json:"spec_definition_id" gorm:"uniqueIndex:idx_spec_values_phone_spec;index"`
+	FeatureID        *uint    `json:"feature_id,omitempty" gorm:"index"`
+	Number           *float64 `json:"number,omitempty" gorm:"column:number_value"`
+	Text             *string  `json:"text,omitempty" gorm:"column:text_value"`
+	Bool             *bool    `json:"bool,omitempty" gorm:"column:bool_value"`
+}
+
+// Spec is a phone's value of a spec together with its definition.
+type Spec struct {
+	Key      string      `json:"key"`
+	Label    string      `json:"label"`
+	DataType string      `json:"data_type"`
+	Unit     string      `json:"unit,omitempty"`
+	Value    interface{} `json:"value"`
+}
+
+type SpecGroup struct {
+	Group string `json:"group"`
+	Specs []Spec `json:"specs"`
+}
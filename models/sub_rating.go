@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+type SubRating struct {
+	gorm.Model `swaggerignore:"true"`
+	ReviewID   uint   `json:"review_id" gorm:"uniqueIndex:idx_sub_ratings_review_dimension" swaggerignore:"true"`
+	Dimension  string `json:"dimension" gorm:"size:64;uniqueIndex:idx_sub_ratings_review_dimension" binding:"required"`
+	Score      int    `json:"score" binding:"rating"`
+}
+
+type DimensionStats struct {
+	Dimension string  `json:"dimension"`
+	Average   float64 `json:"average"`
+	Count     int64   `json:"count"`
+}
@@ -2,10 +2,18 @@ package models
 
 import "gorm.io/gorm"
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	gorm.Model `swaggerignore:"true"`
 	Username   string    `json:"username" gorm:"unique"`
 	Password   string    `json:"password"`
+	Role       string    `json:"role" gorm:"default:user" swaggerignore:"true"`
+	Trusted    bool      `json:"trusted" swaggerignore:"true"`
 	Profile    Profile   `json:"profile" gorm:"foreignkey:UserID"`
 	Reviews    []Review  `json:"reviews"`
 	Comment    []Comment `json:"comments"`
@@ -0,0 +1,45 @@
+package models
+
+import "gorm.io/gorm"
+
+// Variant is a configuration of a phone sold on its own, such as the 256 GB
+// model in black. Its spec values override the phone's, and Specs holds the
+// result.
+type Variant struct {
+	gorm.Model  `swaggerignore:"true"`
+	PhoneID     uint        `json:"phone_id" gorm:"index"`
+	Name        string      `json:"name"`
+	ModelNumber string      `json:"model_number" gorm:"size:50;index"`
+	EAN         *string     `json:"ean" gorm:"size:13;uniqueIndex"`
+	Color       string      `json:"color"`
+	Price       *float64    `json:"price"`
+	Specs       []SpecGroup `json:"specs" gorm:"-"`
+}
+
+// VariantSpecValue is a variant's own value of a spec, kept like SpecValue.
+type VariantSpecValue struct {
+	gorm.Model       `swaggerignore:"true"`
+	VariantID        uint     `json:"variant_id" gorm:"uniqueIndex:idx_variant_spec_values_variant_spec"`
+	SpecDefinitionID uint     `json:"spec_definition_id" gorm:"uniqueIndex:idx_variant_spec_values_variant_spec;index"`
+	Number           *float64 `json:"number,omitempty" gorm:"column:number_value"`
+	Text             *string  `json:"text,omitempty" gorm:"column:text_value"`
+	Bool             *bool    `json:"bool,omitempty" gorm:"column:bool_value"`
+}
+
+type VariantRequest struct {
+	Name        string   `json:"name" binding:"required,max=100,nohtml"`
+	ModelNumber string   `json:"model_number" binding:"max=50,nohtml"`
+	EAN         *string  `json:"ean" binding:"omitempty,ean"`
+	Color       string   `json:"color" binding:"max=50,nohtml"`
+	Price       *float64 `json:"price" binding:"omitempty,gt=0"`
+	// Specs holds the variant's spec values by key. When present it
+	// replaces all of the variant's overrides.
+	Specs map[string]interface{} `json:"specs"`
+}
+
+type VariantStats struct {
+	VariantID     uint    `json:"variant_id"`
+	Name          string  `json:"name"`
+	ReviewCount   int64   `json:"review_count"`
+	AverageRating float64 `json:"average_rating"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookEventPing              = "ping"
+	WebhookEventPhoneCreated      = "phone.created"
+	WebhookEventPhoneUpdated      = "phone.updated"
+	WebhookEventPhoneDeleted      = "phone.deleted"
+	WebhookEventReviewPublished   = "review.published"
+	WebhookEventReviewUpdated     = "review.updated"
+	WebhookEventReviewUnpublished = "review.unpublished"
+	WebhookEventReviewDeleted     = "review.deleted"
+
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryRetrying  = "retrying"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEvents lists the events an endpoint can subscribe to.
+var WebhookEvents = []string{
+	WebhookEventPhoneCreated,
+	WebhookEventPhoneUpdated,
+	WebhookEventPhoneDeleted,
+	WebhookEventReviewPublished,
+	WebhookEventReviewUpdated,
+	WebhookEventReviewUnpublished,
+	WebhookEventReviewDeleted,
+}
+
+type Webhook struct {
+	gorm.Model  `swaggerignore:"true"`
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" gorm:"serializer:json"`
+	// Secret signs every delivery. It is only shown when the endpoint is
+	// registered.
+	Secret    string `json:"secret,omitempty"`
+	Active    bool   `json:"active"`
+	CreatedBy uint   `json:"created_by"`
+}
+
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required,http_url,max=500"`
+	Description string   `json:"description" binding:"max=200,nohtml"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=phone.created phone.updated phone.deleted review.published review.updated review.unpublished review.deleted"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookDelivery is one event sent to one endpoint, with the outcome of
+// its latest attempt. Redeliveries are new rows sharing the EventID.
+type WebhookDelivery struct {
+	gorm.Model     `swaggerignore:"true"`
+	WebhookID      uint       `json:"webhook_id" gorm:"index"`
+	EventID        string     `json:"event_id" gorm:"index"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"default:pending;index"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body" gorm:"type:text"`
+	Error          string     `json:"error"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	RedeliveryOf   *uint      `json:"redelivery_of"`
+}
+
+type WebhookDeliveryPage struct {
+	Items      []WebhookDelivery `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
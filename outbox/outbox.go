@@ -0,0 +1,253 @@
+// Package outbox queues side effects in the database transaction of the
+// change that causes them and runs them later from a worker, so a side
+// effect happens if and only if its change was committed.
+package outbox
+
+import (
+	"backend-vercel-phone-review/models"
+	"backend-vercel-phone-review/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPermanent marks a handler error that retrying cannot fix; the job is
+// dead-lettered straight away. Wrap it with fmt.Errorf("...: %w", ...).
+var ErrPermanent = errors.New("permanent job failure")
+
+// errLeaseLost reports that a job's lease ran out and another worker claimed
+// it again while it ran. The other run owns the job from then on.
+var errLeaseLost = errors.New("job lease lost")
+
+// Handler carries out one job. It runs inside a transaction that also marks
+// the job done, so database writes made through tx happen exactly once.
+// Effects outside the database may repeat when a job is retried. Handlers
+// of the kinds in Worker.OutsideTransaction get the plain database instead.
+type Handler func(ctx context.Context, tx *gorm.DB, payload json.RawMessage) error
+
+// Options tune a single job. The zero value runs it as soon as possible
+// with the worker's default attempt limit.
+type Options struct {
+	RunAt       time.Time
+	MaxAttempts int
+	// Key makes enqueueing the same job more than once a no-op
+	Key string
+}
+
+// Enqueue writes a job through tx, which should be the transaction of the
+// change the job belongs to.
+func Enqueue(tx *gorm.DB, kind string, payload interface{}, opts Options) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	job := models.OutboxJob{
+		Kind:        kind,
+		Payload:     string(raw),
+		Status:      models.OutboxJobPending,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if opts.Key == "" {
+		return tx.Create(&job).Error
+	}
+
+	job.IdempotencyKey = &opts.Key
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&job).Error
+}
+
+// Worker claims due jobs and runs them. Jobs whose worker died mid-run are
+// claimed again once their lease runs out. A failed job is retried with
+// exponential backoff and dead-lettered after its last attempt.
+type Worker struct {
+	DB       *gorm.DB
+	Handlers map[string]Handler
+	// OutsideTransaction lists the job kinds whose handlers make slow calls,
+	// such as HTTP requests, that must not hold a transaction open. They
+	// group their own writes, which may repeat when the job is retried.
+	OutsideTransaction map[string]bool
+
+	BatchSize   int
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Lease       time.Duration
+	// Now is the worker's clock, time.Now when nil
+	Now func() time.Time
+}
+
+func (w *Worker) now() time.Time {
+	if w.Now == nil {
+		return time.Now()
+	}
+	return w.Now()
+}
+
+// RunOnce runs the jobs that are due now, at most BatchSize of them.
+func (w *Worker) RunOnce(ctx context.Context) (models.JobRunSummary, error) {
+	var summary models.JobRunSummary
+
+	now := w.now()
+	var due []models.OutboxJob
+	if err := w.DB.WithContext(ctx).Scopes(claimable(now)).
+		Order("run_at ASC, id ASC").
+		Limit(w.BatchSize).
+		Find(&due).Error; err != nil {
+		return summary, err
+	}
+
+	for _, job := range due {
+		if ctx.Err() != nil {
+			return summary, ctx.Err()
+		}
+
+		claimed, err := w.claim(ctx, &job)
+		if err != nil {
+			return summary, err
+		}
+		if !claimed {
+			continue
+		}
+		summary.Claimed++
+
+		switch status, err := w.run(ctx, job); {
+		case err != nil:
+			return summary, err
+		case status == models.OutboxJobDone:
+			summary.Done++
+		case status == models.OutboxJobDead:
+			summary.Dead++
+		case status == models.OutboxJobRunning:
+			// Another worker claimed the job again and counts it
+		default:
+			summary.Retried++
+		}
+	}
+
+	return summary, nil
+}
+
+// claimable matches jobs that are due, including running jobs whose lease
+// has expired.
+func claimable(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+			models.OutboxJobPending, now, models.OutboxJobRunning, now)
+	}
+}
+
+// claim takes a job for this worker. It fails quietly when another worker
+// got there first.
+func (w *Worker) claim(ctx context.Context, job *models.OutboxJob) (bool, error) {
+	now := w.now()
+	// Kept to the precision the database stores, so the run can match its
+	// claim on it
+	lockedUntil := now.Add(w.Lease).Truncate(time.Millisecond)
+	result := w.DB.WithContext(ctx).Model(&models.OutboxJob{}).
+		Where("id = ?", job.ID).
+		Scopes(claimable(now)).
+		Updates(map[string]interface{}{
+			"status":       models.OutboxJobRunning,
+			"locked_until": lockedUntil,
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	job.Status = models.OutboxJobRunning
+	job.LockedUntil = &lockedUntil
+	job.Attempts++
+	return true, nil
+}
+
+// run executes a claimed job and records its new status.
+func (w *Worker) run(ctx context.Context, job models.OutboxJob) (string, error) {
+	handler, ok := w.Handlers[job.Kind]
+	if !ok {
+		return w.finish(ctx, job, models.OutboxJobDead, fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+
+	runJob := func(db *gorm.DB) error {
+		if err := handler(ctx, db, json.RawMessage(job.Payload)); err != nil {
+			return err
+		}
+		now := w.now()
+		result := db.Model(&job).Scopes(heldBy(job)).Updates(map[string]interface{}{
+			"status":       models.OutboxJobDone,
+			"locked_until": nil,
+			"completed_at": now,
+			"last_error":   "",
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Rolls back the handler's writes
+			return errLeaseLost
+		}
+		return nil
+	}
+
+	var err error
+	if w.OutsideTransaction[job.Kind] {
+		err = runJob(w.DB.WithContext(ctx))
+	} else {
+		err = w.DB.WithContext(ctx).Transaction(runJob)
+	}
+	if err == nil {
+		return models.OutboxJobDone, nil
+	}
+	if errors.Is(err, errLeaseLost) {
+		return models.OutboxJobRunning, nil
+	}
+
+	maxAttempts := job.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = w.MaxAttempts
+	}
+	if job.Attempts >= maxAttempts || errors.Is(err, ErrPermanent) {
+		return w.finish(ctx, job, models.OutboxJobDead, err)
+	}
+	return w.finish(ctx, job, models.OutboxJobPending, err)
+}
+
+// finish records a failed attempt, scheduling the next one when the job
+// goes back to pending, and returns the job's new status. A job that was
+// claimed again in the meantime is left as it is.
+func (w *Worker) finish(ctx context.Context, job models.OutboxJob, status string, cause error) (string, error) {
+	updates := map[string]interface{}{
+		"status":       status,
+		"locked_until": nil,
+		"last_error":   cause.Error(),
+	}
+	if status == models.OutboxJobPending {
+		updates["run_at"] = w.now().Add(utils.Backoff(job.Attempts, w.BaseDelay, w.MaxDelay))
+	}
+	result := w.DB.WithContext(ctx).Model(&job).Scopes(heldBy(job)).Updates(updates)
+	if result.Error != nil {
+		return status, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.OutboxJobRunning, nil
+	}
+	return status, nil
+}
+
+// heldBy matches a job only while it still holds the claim it was run
+// under, so a run that outlived its lease leaves the job to the worker that
+// claimed it again.
+func heldBy(job models.OutboxJob) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("attempts = ? AND locked_until = ?", job.Attempts, *job.LockedUntil)
+	}
+}
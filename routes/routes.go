@@ -3,6 +3,7 @@ package routes
 import (
 	"backend-vercel-phone-review/controllers"
 	"backend-vercel-phone-review/middleware"
+	"backend-vercel-phone-review/models"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -28,29 +29,81 @@ func SetupRouter() *gin.Engine {
 		{
 			userRoutes.GET("/:id", controllers.GetUser)
 			userRoutes.PUT("/:id/profile", controllers.UpdateProfile)
+			userRoutes.PUT("/:id/follow", controllers.FollowUser)
+			userRoutes.DELETE("/:id/follow", controllers.UnfollowUser)
 		}
 
 		phoneRoutes := api.Group("/phones")
 		{
 			phoneRoutes.GET("/", controllers.GetPhones)
 			phoneRoutes.POST("/", middleware.JWTAuthMiddleware(), controllers.CreatePhone)
+			phoneRoutes.GET("/compare", controllers.ComparePhones)
 			phoneRoutes.POST("/:phone_id/features", middleware.JWTAuthMiddleware(), controllers.CreateFeature)
+			phoneRoutes.PUT("/:phone_id/specs", middleware.JWTAuthMiddleware(), controllers.UpdatePhoneSpecs)
+			phoneRoutes.GET("/:phone_id/variants", controllers.GetVariants)
+			phoneRoutes.POST("/:phone_id/variants", middleware.JWTAuthMiddleware(), controllers.CreateVariant)
+			phoneRoutes.PUT("/:phone_id/variants/:variant_id", middleware.JWTAuthMiddleware(), controllers.UpdateVariant)
+			phoneRoutes.DELETE("/:phone_id/variants/:variant_id", middleware.JWTAuthMiddleware(), controllers.DeleteVariant)
 			phoneRoutes.PUT("/:phone_id", middleware.JWTAuthMiddleware(), controllers.UpdatePhone)
 			phoneRoutes.GET("/:phone_id", controllers.GetPhoneByID)
+			phoneRoutes.GET("/:phone_id/stats", controllers.GetPhoneStats)
+			phoneRoutes.GET("/:phone_id/reviews", controllers.GetReviews)
+			phoneRoutes.GET("/:phone_id/reviews/stream", controllers.StreamPhoneReviews)
+			phoneRoutes.PUT("/:phone_id/my-review", middleware.JWTAuthMiddleware(), controllers.UpsertMyReview)
+			phoneRoutes.PUT("/:phone_id/follow", middleware.JWTAuthMiddleware(), controllers.FollowPhone)
+			phoneRoutes.DELETE("/:phone_id/follow", middleware.JWTAuthMiddleware(), controllers.UnfollowPhone)
 			phoneRoutes.DELETE("/:phone_id", middleware.JWTAuthMiddleware(), controllers.DeletePhone)
 			phoneRoutes.PUT("/:phone_id/features/:feature_id", middleware.JWTAuthMiddleware(), controllers.UpdateFeature)
 			phoneRoutes.DELETE("/:phone_id/features/:feature_id", middleware.JWTAuthMiddleware(), controllers.DeleteFeature)
 		}
 
+		brandRoutes := api.Group("/brands")
+		{
+			brandRoutes.GET("/", controllers.GetBrands)
+			brandRoutes.POST("/", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.CreateBrand)
+			brandRoutes.GET("/:slug", controllers.GetBrand)
+			brandRoutes.PUT("/:slug", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.UpdateBrand)
+			brandRoutes.GET("/:slug/phones", controllers.GetBrandPhones)
+			brandRoutes.PUT("/:slug/follow", middleware.JWTAuthMiddleware(), controllers.FollowBrand)
+			brandRoutes.DELETE("/:slug/follow", middleware.JWTAuthMiddleware(), controllers.UnfollowBrand)
+		}
+
+		specRoutes := api.Group("/specs")
+		{
+			specRoutes.GET("/", controllers.GetSpecDefinitions)
+			specRoutes.POST("/", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.CreateSpecDefinition)
+			specRoutes.POST("/reparse", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.ReparseFeatures)
+			specRoutes.PUT("/:key", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.UpdateSpecDefinition)
+			specRoutes.DELETE("/:key", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.DeleteSpecDefinition)
+		}
+
+		api.GET("/follows", middleware.JWTAuthMiddleware(), controllers.GetMyFollows)
+		api.GET("/feed", middleware.JWTAuthMiddleware(), controllers.GetFeed)
+
+		digestRoutes := api.Group("/digest")
+		{
+			digestRoutes.GET("/", middleware.JWTAuthMiddleware(), controllers.GetDigestSubscription)
+			digestRoutes.PUT("/", middleware.JWTAuthMiddleware(), controllers.UpdateDigestSubscription)
+			digestRoutes.DELETE("/", middleware.JWTAuthMiddleware(), controllers.DeleteDigestSubscription)
+			digestRoutes.GET("/confirm", controllers.ConfirmDigestPage)
+			digestRoutes.POST("/confirm", controllers.ConfirmDigest)
+			digestRoutes.GET("/unsubscribe", controllers.UnsubscribeDigestPage)
+			digestRoutes.POST("/unsubscribe", controllers.UnsubscribeDigest)
+		}
+
 		reviewRoutes := api.Group("/reviews")
 
 		{
 			reviewRoutes.POST("/", middleware.JWTAuthMiddleware(), controllers.CreateReview)
 			reviewRoutes.GET("/", controllers.GetAllReviews)
 			reviewRoutes.GET("/:id", controllers.GetReviewByID)
-			// reviewRoutes.GET("/:phone_id", controllers.GetReviews)
+			reviewRoutes.GET("/:id/revisions", controllers.GetReviewRevisions)
+			reviewRoutes.GET("/:id/comments/stream", controllers.StreamReviewComments)
 			reviewRoutes.PUT("/:id", middleware.JWTAuthMiddleware(), controllers.UpdateReview)
 			reviewRoutes.DELETE("/:id", middleware.JWTAuthMiddleware(), controllers.DeleteReview)
+			reviewRoutes.PUT("/:id/vote", middleware.JWTAuthMiddleware(), controllers.VoteReview)
+			reviewRoutes.DELETE("/:id/vote", middleware.JWTAuthMiddleware(), controllers.DeleteVote)
+			reviewRoutes.POST("/:id/reports", middleware.JWTAuthMiddleware(), controllers.ReportReview)
 		}
 
 		commentRoutes := api.Group("/comments")
@@ -60,6 +113,56 @@ func SetupRouter() *gin.Engine {
 			commentRoutes.GET("/:review_id", controllers.GetComments)
 			commentRoutes.PUT("/:id", controllers.UpdateComment)
 			commentRoutes.DELETE("/:id", controllers.DeleteComment)
+			commentRoutes.POST("/:id/reports", controllers.ReportComment)
+		}
+
+		api.GET("/notifications/stream", middleware.StreamTicketMiddleware(), controllers.StreamNotifications)
+
+		notificationRoutes := api.Group("/notifications")
+		notificationRoutes.Use(middleware.JWTAuthMiddleware())
+		{
+			notificationRoutes.GET("/", controllers.GetNotifications)
+			notificationRoutes.POST("/read-all", controllers.MarkAllNotificationsRead)
+			notificationRoutes.POST("/stream/ticket", controllers.CreateStreamTicket)
+			notificationRoutes.GET("/preferences", controllers.GetNotificationPreferences)
+			notificationRoutes.PUT("/preferences", controllers.UpdateNotificationPreferences)
+			notificationRoutes.POST("/:id/read", controllers.MarkNotificationRead)
+		}
+
+		moderationRoutes := api.Group("/moderation")
+		moderationRoutes.Use(middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+		{
+			moderationRoutes.GET("/reviews", controllers.GetModerationQueue)
+			moderationRoutes.POST("/reviews/:id/approve", controllers.ApproveReview)
+			moderationRoutes.POST("/reviews/:id/reject", controllers.RejectReview)
+			moderationRoutes.POST("/reviews/:id/hide", controllers.HideReview)
+			moderationRoutes.POST("/reviews/:id/verify", controllers.VerifyReview)
+			moderationRoutes.GET("/comments", controllers.GetCommentModerationQueue)
+			moderationRoutes.POST("/comments/:id/approve", controllers.ApproveComment)
+			moderationRoutes.POST("/comments/:id/reject", controllers.RejectComment)
+			moderationRoutes.GET("/reports", controllers.GetReportQueue)
+			moderationRoutes.POST("/reports/:target_type/:id/dismiss", controllers.DismissReports)
+			moderationRoutes.POST("/reports/:target_type/:id/uphold", controllers.UpholdReports)
+		}
+
+		webhookRoutes := api.Group("/webhooks")
+		webhookRoutes.Use(middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin))
+		{
+			webhookRoutes.GET("/", controllers.GetWebhooks)
+			webhookRoutes.POST("/", controllers.CreateWebhook)
+			webhookRoutes.GET("/:id", controllers.GetWebhook)
+			webhookRoutes.PUT("/:id", controllers.UpdateWebhook)
+			webhookRoutes.DELETE("/:id", controllers.DeleteWebhook)
+			webhookRoutes.POST("/:id/ping", controllers.PingWebhook)
+			webhookRoutes.GET("/:id/deliveries", controllers.GetWebhookDeliveries)
+			webhookRoutes.POST("/:id/deliveries/:delivery_id/redeliver", controllers.RedeliverWebhook)
+		}
+
+		jobRoutes := api.Group("/jobs")
+		{
+			jobRoutes.GET("/run", middleware.RequireCronSecret(), controllers.RunJobs)
+			jobRoutes.GET("/", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.GetJobs)
+			jobRoutes.POST("/:id/retry", middleware.JWTAuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.RetryJob)
 		}
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
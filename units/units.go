@@ -0,0 +1,166 @@
+// Package units parses quantities as catalog editors type them, such as
+// "6.7 in", "170mm", "5,000 mAh" or "12GB", and converts them between
+// units of the same kind.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoNumber     = errors.New("no number found")
+	ErrUnknownUnit  = errors.New("unknown unit")
+	ErrIncompatible = errors.New("incompatible units")
+)
+
+// Quantity is a parsed value in its canonical unit spelling. Unit is empty
+// for a bare number.
+type Quantity struct {
+	Value float64
+	Unit  string
+}
+
+type unit struct {
+	name string
+	kind string
+	// factor converts a value in this unit to the base unit of its kind
+	factor float64
+}
+
+var catalog = []struct {
+	unit
+	aliases []string
+}{
+	{unit{"mm", "length", 1}, []string{"mm", "millimeter", "millimeters", "millimetre", "millimetres"}},
+	{unit{"cm", "length", 10}, []string{"cm", "centimeter", "centimeters", "centimetre", "centimetres"}},
+	{unit{"in", "length", 25.4}, []string{"in", "inch", "inches", `"`, "″", "”", "''"}},
+	{unit{"g", "mass", 1}, []string{"g", "gr", "gram", "grams"}},
+	{unit{"kg", "mass", 1000}, []string{"kg", "kilogram", "kilograms"}},
+	{unit{"oz", "mass", 28.349523125}, []string{"oz", "ounce", "ounces"}},
+	{unit{"mAh", "charge", 1}, []string{"mah"}},
+	{unit{"Ah", "charge", 1000}, []string{"ah"}},
+	{unit{"Wh", "energy", 1}, []string{"wh"}},
+	{unit{"KB", "data", 1.0 / 1024}, []string{"kb"}},
+	{unit{"MB", "data", 1}, []string{"mb"}},
+	{unit{"GB", "data", 1024}, []string{"gb"}},
+	{unit{"TB", "data", 1024 * 1024}, []string{"tb"}},
+	{unit{"Hz", "frequency", 1}, []string{"hz", "hertz"}},
+	{unit{"kHz", "frequency", 1e3}, []string{"khz"}},
+	{unit{"MHz", "frequency", 1e6}, []string{"mhz"}},
+	{unit{"GHz", "frequency", 1e9}, []string{"ghz"}},
+	{unit{"W", "power", 1}, []string{"w", "watt", "watts"}},
+	{unit{"MP", "resolution", 1}, []string{"mp", "megapixel", "megapixels"}},
+	{unit{"%", "ratio", 1}, []string{"%", "percent"}},
+	{unit{"nits", "brightness", 1}, []string{"nits", "nit", "cd/m2", "cd/m²"}},
+	{unit{"ppi", "density", 1}, []string{"ppi"}},
+}
+
+var aliases = func() map[string]unit {
+	byAlias := make(map[string]unit)
+	for _, entry := range catalog {
+		for _, alias := range entry.aliases {
+			byAlias[alias] = entry.unit
+		}
+	}
+	return byAlias
+}()
+
+var (
+	quantityPattern  = regexp.MustCompile(`^([+-]?[0-9][0-9.,\s\x{00A0}\x{202F}]*)(.*)$`)
+	thousandsPattern = regexp.MustCompile(`^[0-9]{1,3}(,[0-9]{3})+(\.[0-9]+)?$`)
+)
+
+// Parse reads a number followed by an optional unit. Commas and spaces may
+// group thousands ("5,000", "5 000"); a lone comma without a dot is read
+// as a decimal comma ("6,7").
+func Parse(s string) (Quantity, error) {
+	match := quantityPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Quantity{}, fmt.Errorf("%w in %q", ErrNoNumber, s)
+	}
+
+	value, err := parseNumber(match[1])
+	if err != nil {
+		return Quantity{}, fmt.Errorf("%w in %q", ErrNoNumber, s)
+	}
+
+	// "6.7-inch" and "6.7 inch display" both name the inch
+	rest := strings.TrimLeft(strings.TrimSpace(match[2]), "-")
+	if rest == "" {
+		return Quantity{Value: value}, nil
+	}
+	name := strings.ToLower(strings.Fields(rest)[0])
+	u, ok := aliases[name]
+	if !ok {
+		return Quantity{}, fmt.Errorf("%w %q", ErrUnknownUnit, strings.Fields(rest)[0])
+	}
+	return Quantity{Value: value, Unit: u.name}, nil
+}
+
+// Canonical returns the canonical spelling of a unit, such as "mAh" for
+// "MAH", and whether the unit is known.
+func Canonical(name string) (string, bool) {
+	u, ok := aliases[strings.ToLower(strings.TrimSpace(name))]
+	return u.name, ok
+}
+
+// Convert expresses q in the unit named to. A bare number is taken to be
+// in that unit already.
+func Convert(q Quantity, to string) (float64, error) {
+	if q.Unit == "" || strings.EqualFold(q.Unit, to) {
+		return q.Value, nil
+	}
+	if to == "" {
+		return 0, fmt.Errorf("%w: expected a plain number, not %s", ErrIncompatible, q.Unit)
+	}
+
+	from := aliases[strings.ToLower(q.Unit)]
+	target, ok := aliases[strings.ToLower(to)]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, to)
+	}
+	if from.kind != target.kind {
+		return 0, fmt.Errorf("%w: cannot convert %s to %s", ErrIncompatible, q.Unit, target.name)
+	}
+
+	return round(q.Value * from.factor / target.factor), nil
+}
+
+// ParseAs parses s and converts it to the unit named to.
+func ParseAs(s, to string) (float64, error) {
+	q, err := Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	return Convert(q, to)
+}
+
+func parseNumber(raw string) (float64, error) {
+	number := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\u00a0', '\u202f':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+	number = strings.TrimRight(number, ".,")
+
+	switch {
+	case thousandsPattern.MatchString(number):
+		number = strings.ReplaceAll(number, ",", "")
+	case strings.Count(number, ",") == 1 && !strings.Contains(number, "."):
+		number = strings.Replace(number, ",", ".", 1)
+	}
+
+	return strconv.ParseFloat(number, 64)
+}
+
+// round drops the floating point noise conversions leave behind.
+func round(value float64) float64 {
+	return math.Round(value*1e6) / 1e6
+}
@@ -0,0 +1,70 @@
+package units
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Quantity
+		err  error
+	}{
+		{"6.7 in", Quantity{6.7, "in"}, nil},
+		{"6.7-inch", Quantity{6.7, "in"}, nil},
+		{`6.1"`, Quantity{6.1, "in"}, nil},
+		{"170mm", Quantity{170, "mm"}, nil},
+		{"5,000 mAh", Quantity{5000, "mAh"}, nil},
+		{"5 000 MAH", Quantity{5000, "mAh"}, nil},
+		{"6,7 inches", Quantity{6.7, "in"}, nil},
+		{"12GB", Quantity{12, "GB"}, nil},
+		{"120 Hz display", Quantity{120, "Hz"}, nil},
+		{"1,234,567.5", Quantity{1234567.5, ""}, nil},
+		{" 42 ", Quantity{42, ""}, nil},
+		{"-3 %", Quantity{-3, "%"}, nil},
+		{"about 6 in", Quantity{}, ErrNoNumber},
+		{"", Quantity{}, ErrNoNumber},
+		{"6 parsecs", Quantity{}, ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAs(t *testing.T) {
+	tests := []struct {
+		in, to string
+		want   float64
+		err    error
+	}{
+		{"6.7 in", "mm", 170.18, nil},
+		{"170 mm", "cm", 17, nil},
+		{"1 TB", "GB", 1024, nil},
+		{"5 Ah", "mAh", 5000, nil},
+		{"2.5 GHz", "MHz", 2500, nil},
+		{"200", "g", 200, nil},
+		{"200 g", "", 0, ErrIncompatible},
+		{"6.7 in", "g", 0, ErrIncompatible},
+		{"6.7 in", "furlong", 0, ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAs(tt.in, tt.to)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseAs(%q, %q) error = %v, want %v", tt.in, tt.to, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAs(%q, %q) = %v, want %v", tt.in, tt.to, got, tt.want)
+		}
+	}
+}
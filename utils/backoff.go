@@ -0,0 +1,16 @@
+package utils
+
+import "time"
+
+// Backoff is the wait before retry number attempt (starting at 1): base
+// doubled for every earlier retry, capped at max.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
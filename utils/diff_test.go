@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          []DiffOp
+	}{
+		{"unchanged", "great battery life", "great battery  life", []DiffOp{{DiffEqual, "great battery life"}}},
+		{"both empty", "", "", nil},
+		{"written", "", "great phone", []DiffOp{{DiffInsert, "great phone"}}},
+		{"cleared", "great phone", "", []DiffOp{{DiffDelete, "great phone"}}},
+		{
+			"replaced word",
+			"the battery is great",
+			"the battery is poor",
+			[]DiffOp{{DiffEqual, "the battery is"}, {DiffDelete, "great"}, {DiffInsert, "poor"}},
+		},
+		{
+			"inserted and deleted",
+			"fast charging and a bright screen",
+			"very fast charging and screen",
+			[]DiffOp{{DiffInsert, "very"}, {DiffEqual, "fast charging and"}, {DiffDelete, "a bright"}, {DiffEqual, "screen"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffWords(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffWords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
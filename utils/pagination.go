@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Cursor marks the last item of a page in keyset pagination. Only the
+// fields used by the listing's sort order are filled in.
+type Cursor struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"t,omitempty"`
+	Score     int       `json:"s,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor turns a cursor into the opaque string handed to clients.
+func EncodeCursor(cursor Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor.
+func DecodeCursor(s string) (Cursor, error) {
+	var cursor Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// ParseLimit reads a page size, using fallback when it is empty and capping
+// it at max.
+func ParseLimit(raw string, fallback, max int) (int, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive number")
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit, nil
+}
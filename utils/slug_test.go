@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Google Pixel", "google-pixel"},
+		{"  Samsung  Galaxy S24 Ultra ", "samsung-galaxy-s24-ultra"},
+		{"Sony Xperia 1 V!", "sony-xperia-1-v"},
+		{"OnePlus--12R", "oneplus-12r"},
+		{"Xiaomi 14 (Pro)", "xiaomi-14-pro"},
+		{"Motorola_Edge", "motorola-edge"},
+		{"Éclair Phone", "éclair-phone"},
+		{"!!!", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Slugify(tt.name); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignToken(t *testing.T) {
+	secret := []byte("mail-secret")
+	token := SignToken(secret, "digest:42")
+
+	value, err := VerifyToken(secret, token)
+	if err != nil || value != "digest:42" {
+		t.Fatalf("VerifyToken() = %q, %v", value, err)
+	}
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+	}{
+		{"wrong secret", []byte("other"), token},
+		{"no signature", secret, "ZGlnZXN0OjQy"},
+		{"tampered value", secret, strings.Split(SignToken(secret, "digest:43"), ".")[0] + "." + strings.Split(token, ".")[1]},
+		{"bad signature", secret, strings.Split(token, ".")[0] + ".!!!"},
+		{"empty", secret, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyToken(tt.secret, tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("VerifyToken() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+var htmlTagPattern = regexp.MustCompile(`<\s*/?\s*[a-zA-Z!][^>]*>`)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// RegisterValidators installs the custom binding tags used by the models
+// on gin's validator and reports field names by their JSON tag.
+func RegisterValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected binding validator engine")
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	if err := v.RegisterValidation("rating", validateRating); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("nohtml", validateNoHTML); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("ean", validateEAN); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateRating(fl validator.FieldLevel) bool {
+	rating := fl.Field().Int()
+	return rating >= MinRating && rating <= MaxRating
+}
+
+func validateNoHTML(fl validator.FieldLevel) bool {
+	return !ContainsHTML(fl.Field().String())
+}
+
+// validateEAN accepts EAN-8 and EAN-13 barcodes with a correct check digit.
+func validateEAN(fl validator.FieldLevel) bool {
+	code := fl.Field().String()
+	if len(code) != 8 && len(code) != 13 {
+		return false
+	}
+
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		// Counting from the check digit, every second digit weighs 3
+		if (len(code)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+func ContainsHTML(s string) bool {
+	return htmlTagPattern.MatchString(s)
+}
+
+// RespondBindError answers a failed ShouldBindJSON. Validation failures are
+// reported field by field, anything else (malformed JSON) as a plain error.
+func RespondBindError(c *gin.Context, err error) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var fields []FieldError
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fieldErr.Namespace()),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+
+	RespondValidationError(c, fields)
+}
+
+func RespondValidationError(c *gin.Context, fields []FieldError) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "fields": fields})
+}
+
+// fieldPath drops the top-level struct name from a validator namespace,
+// turning "Review.sub_ratings[0].score" into "sub_ratings[0].score".
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	verb, unit := "be", ""
+	switch fieldErr.Kind() {
+	case reflect.String:
+		unit = " characters long"
+	case reflect.Slice:
+		verb, unit = "have", " items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "rating":
+		return fmt.Sprintf("must be between %d and %d", MinRating, MaxRating)
+	case "nohtml":
+		return "must not contain HTML"
+	case "ean":
+		return "must be a valid EAN-8 or EAN-13 barcode"
+	case "bcp47_language_tag":
+		return "must be a language tag such as en or pt-BR"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fieldErr.Param()), ", ")
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email address"
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "min":
+		return fmt.Sprintf("must %s at least %s%s", verb, fieldErr.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must %s at most %s%s", verb, fieldErr.Param(), unit)
+	default:
+		return fmt.Sprintf("failed the %q check", fieldErr.Tag())
+	}
+}
@@ -0,0 +1,158 @@
+// Package webhooks signs and sends webhook deliveries. Receivers check the
+// signature with Verify.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	// maxResponseBody is how much of a receiver's response is kept in the
+	// delivery log.
+	maxResponseBody = 2048
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("webhook signature timestamp is too old")
+)
+
+// Payload is the JSON body of every delivery. ID identifies the event and
+// stays the same across retries and redeliveries, so receivers can use it
+// to ignore duplicates.
+type Payload struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewPayload encodes data as the body of an event.
+func NewPayload(event string, data interface{}) (Payload, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Payload{}, err
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return Payload{}, err
+	}
+
+	return Payload{ID: "evt_" + id, Event: event, CreatedAt: time.Now().UTC(), Data: raw}, nil
+}
+
+// NewSecret generates a signing secret for a new endpoint.
+func NewSecret() (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + secret, nil
+}
+
+// Sign returns the signature header for body sent at timestamp, in the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">".
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, signature(secret, ts, body))
+}
+
+// Verify checks a signature header produced by Sign against the body that
+// was received. Signatures older than tolerance are rejected to stop
+// replays; a zero tolerance skips that check.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return ErrExpiredSignature
+	}
+
+	expected := signature(secret, ts, body)
+	for _, candidate := range signatures {
+		if hmac.Equal([]byte(candidate), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Response is what a receiver answered to a delivery.
+type Response struct {
+	StatusCode int
+	Body       string
+}
+
+// Send posts a signed delivery to url. Any answer outside 2xx is returned
+// as an error along with the response.
+func Send(ctx context.Context, client *http.Client, url, secret, event, deliveryID string, body []byte) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "phone-review-webhooks/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer res.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+	response := Response{StatusCode: res.StatusCode, Body: string(raw)}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return response, fmt.Errorf("receiver answered %s", res.Status)
+	}
+	return response, nil
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"review.published"}`)
+	now := time.Now()
+	// During a secret rotation receivers may get a signature per secret
+	rotated := Sign("whsec_old", now, body) + "," + strings.SplitN(Sign("whsec_a", now, body), ",", 2)[1]
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		want      error
+	}{
+		{"valid", "whsec_a", Sign("whsec_a", now, body), body, 5 * time.Minute, nil},
+		{"no tolerance", "whsec_a", Sign("whsec_a", now.Add(-time.Hour), body), body, 0, nil},
+		{"wrong secret", "whsec_b", Sign("whsec_a", now, body), body, 5 * time.Minute, ErrInvalidSignature},
+		{"tampered body", "whsec_a", Sign("whsec_a", now, body), []byte(`{}`), 5 * time.Minute, ErrInvalidSignature},
+		{"expired", "whsec_a", Sign("whsec_a", now.Add(-time.Hour), body), body, 5 * time.Minute, ErrExpiredSignature},
+		{"rotated secret", "whsec_a", rotated, body, 5 * time.Minute, nil},
+		{"no timestamp", "whsec_a", "v1=abc", body, 0, ErrInvalidSignature},
+		{"no signature", "whsec_a", "t=123", body, 0, ErrInvalidSignature},
+		{"empty", "whsec_a", "", body, 0, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.body, tt.tolerance); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}